@@ -0,0 +1,119 @@
+package ginpprof
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aclstack/gin-pprof/pkg/adapters/config"
+	"github.com/aclstack/gin-pprof/pkg/core"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRoutes registers the runtime task admin API on group:
+//
+//	GET    /tasks      list runtime tasks
+//	POST   /tasks      create a runtime task
+//	PUT    /tasks/:id  replace a runtime task
+//	DELETE /tasks/:id  cancel a runtime task
+//
+// Runtime tasks are merged with the file or Nacos tasks and override tasks
+// with the same ID. The group should be protected by authentication middleware.
+func (p *Profiler) AdminRoutes(group *gin.RouterGroup) {
+	group.GET("/tasks", p.listRuntimeTasks)
+	group.POST("/tasks", p.createRuntimeTask)
+	group.PUT("/tasks/:id", p.updateRuntimeTask)
+	group.DELETE("/tasks/:id", p.deleteRuntimeTask)
+}
+
+// listRuntimeTasks returns the runtime tasks
+func (p *Profiler) listRuntimeTasks(c *gin.Context) {
+	if p.runtime == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Runtime tasks not available"})
+		return
+	}
+
+	tasks, _ := p.runtime.GetTasks(c.Request.Context())
+	c.JSON(http.StatusOK, gin.H{
+		"tasks": tasks,
+		"total": len(tasks),
+	})
+}
+
+// createRuntimeTask creates a runtime task from the request body
+func (p *Profiler) createRuntimeTask(c *gin.Context) {
+	task, ok := p.bindRuntimeTask(c)
+	if !ok {
+		return
+	}
+
+	created, err := p.runtime.Create(task)
+	if err != nil {
+		p.writeRuntimeTaskError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// updateRuntimeTask replaces a runtime task with the request body
+func (p *Profiler) updateRuntimeTask(c *gin.Context) {
+	task, ok := p.bindRuntimeTask(c)
+	if !ok {
+		return
+	}
+
+	updated, err := p.runtime.Update(c.Param("id"), task)
+	if err != nil {
+		p.writeRuntimeTaskError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// deleteRuntimeTask cancels a runtime task
+func (p *Profiler) deleteRuntimeTask(c *gin.Context) {
+	if p.runtime == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Runtime tasks not available"})
+		return
+	}
+
+	id := c.Param("id")
+	if err := p.runtime.Delete(id); err != nil {
+		p.writeRuntimeTaskError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// bindRuntimeTask decodes a task from the request body
+func (p *Profiler) bindRuntimeTask(c *gin.Context) (core.ProfilingTask, bool) {
+	if p.runtime == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Runtime tasks not available"})
+		return core.ProfilingTask{}, false
+	}
+
+	var task core.ProfilingTask
+	if err := c.ShouldBindJSON(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return core.ProfilingTask{}, false
+	}
+
+	return task, true
+}
+
+// writeRuntimeTaskError maps runtime task errors to HTTP responses
+func (p *Profiler) writeRuntimeTaskError(c *gin.Context, err error) {
+	var validationErr *core.TaskValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "invalid task",
+			"errors": validationErr.Errors,
+		})
+	case errors.Is(err, config.ErrTaskExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, config.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,147 @@
+package ginpprof
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+	"github.com/gin-gonic/gin"
+)
+
+// staticProvider is a config provider that returns fixed tasks
+type staticProvider struct {
+	tasks []core.ProfilingTask
+}
+
+func (p *staticProvider) GetTasks(ctx context.Context) ([]core.ProfilingTask, error) {
+	return p.tasks, nil
+}
+
+func (p *staticProvider) Subscribe(ctx context.Context, callback func([]core.ProfilingTask)) error {
+	return nil
+}
+
+func (p *staticProvider) Close() error { return nil }
+
+// newTestProfiler builds a profiler with in-memory storage and no logging
+func newTestProfiler(t *testing.T, b *Builder) *Profiler {
+	t.Helper()
+	p := b.WithNoLogger().WithMemoryStorage().Build()
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// waitForTask waits until the manager has the task with id and check accepts it
+func waitForTask(t *testing.T, p *Profiler, id string, check func(core.ProfilingTask) bool) core.ProfilingTask {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if task, exists := p.GetTasks()[id]; exists && check(task) {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("manager did not load task %q: %v", id, p.GetTasks())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// adminRequest sends a JSON request to the admin routes and decodes the task
+// in the response
+func adminRequest(t *testing.T, engine *gin.Engine, method, path, body string, wantStatus int) core.ProfilingTask {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(recorder, req)
+	if recorder.Code != wantStatus {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, recorder.Code, recorder.Body, wantStatus)
+	}
+
+	var task core.ProfilingTask
+	if err := json.Unmarshal(recorder.Body.Bytes(), &task); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func TestAdminUpdateExtendsTTL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	p := newTestProfiler(t, New().WithConfigProvider(&staticProvider{}))
+	engine := gin.New()
+	p.AdminRoutes(engine.Group("/admin"))
+
+	created := adminRequest(t, engine, http.MethodPost, "/admin/tasks",
+		`{"id":"orders","path":"/api/orders","methods":["GET"],"profile_type":"cpu","ttl":"1h"}`, http.StatusCreated)
+	waitForTask(t, p, "orders", func(task core.ProfilingTask) bool {
+		return task.ExpiresAt.Equal(created.ExpiresAt)
+	})
+
+	// The provider resolved the new ttl from the update time; the manager must
+	// not move it back to the time the task was first seen
+	time.Sleep(20 * time.Millisecond)
+	updated := adminRequest(t, engine, http.MethodPut, "/admin/tasks/orders",
+		`{"path":"/api/orders","methods":["GET"],"profile_type":"cpu","ttl":"2h"}`, http.StatusOK)
+	if !updated.ExpiresAt.After(created.ExpiresAt.Add(time.Hour)) {
+		t.Fatalf("admin API returned ExpiresAt %v, created at %v", updated.ExpiresAt, created.ExpiresAt)
+	}
+
+	task := waitForTask(t, p, "orders", func(task core.ProfilingTask) bool {
+		return task.TTL == 2*time.Hour
+	})
+	if !task.ExpiresAt.Equal(updated.ExpiresAt) {
+		t.Fatalf("manager ExpiresAt = %v, admin API returned %v", task.ExpiresAt, updated.ExpiresAt)
+	}
+}
+
+// customProfiler is a profiler for a custom profile type
+type customProfiler struct{}
+
+func (customProfiler) StartProfiling(ctx context.Context, task core.ProfilingTask) (core.ProfileSession, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (customProfiler) GetProfileType() string { return "builder_custom" }
+
+func TestBuilderRegistersProfilersBeforeConfigSync(t *testing.T) {
+	task := core.ProfilingTask{
+		ID:          "custom",
+		Path:        "/api/orders",
+		Methods:     []string{"GET"},
+		ProfileType: "builder_custom",
+		Duration:    10,
+		SampleRate:  1,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	provider := &syncCheckProvider{staticProvider: staticProvider{tasks: []core.ProfilingTask{task}}}
+	p := newTestProfiler(t, New().WithConfigProvider(provider).WithProfiler(customProfiler{}))
+
+	waitForTask(t, p, "custom", func(core.ProfilingTask) bool { return true })
+	if !provider.knownAtSync.Load() {
+		t.Fatal("custom profile type was not registered when the tasks were first loaded")
+	}
+	if quarantined := p.manager.GetQuarantinedTasks(); len(quarantined) != 0 {
+		t.Fatalf("quarantined tasks: %v", quarantined)
+	}
+}
+
+// syncCheckProvider records whether the custom profile type was known when
+// the manager first loaded its tasks
+type syncCheckProvider struct {
+	staticProvider
+	once        sync.Once
+	knownAtSync atomic.Bool
+}
+
+func (p *syncCheckProvider) GetTasks(ctx context.Context) ([]core.ProfilingTask, error) {
+	p.once.Do(func() { p.knownAtSync.Store(core.IsKnownProfileType("builder_custom")) })
+	return p.staticProvider.GetTasks(ctx)
+}
@@ -61,6 +61,7 @@ func main() {
 		debug.GET("/status", profiler.StatusHandler())
 		debug.GET("/tasks", profiler.TasksHandler())
 		debug.GET("/stats", profiler.StatsHandler())
+		debug.GET("/profiles", profiler.ProfilesHandler())
 	}
 
 	// Sample application endpoints
@@ -2,10 +2,11 @@ package ginpprof
 
 import (
 	"context"
-	"time"
+	"runtime/pprof"
 
 	"github.com/gin-gonic/gin"
 	"github.com/aclstack/gin-pprof/pkg/adapters/http"
+	"github.com/aclstack/gin-pprof/pkg/core"
 )
 
 // Middleware 为动态性能分析创建一个Gin中间件
@@ -22,75 +23,82 @@ func (p *Profiler) Middleware() gin.HandlerFunc {
 		path := httpCtx.GetPath()
 		method := httpCtx.GetMethod()
 
-		// 检查是否应该对此请求进行性能分析
-		task, shouldProfile := p.manager.ShouldProfile(path, method)
+		// 检查是否应该对此请求进行性能分析（包括按需触发）
+		task, shouldProfile := p.manager.ShouldProfileRequest(httpCtx)
 		if !shouldProfile {
 			c.Next()
 			return
 		}
 
-		// 开始性能分析
-		ctx := context.Background()
-		session, err := p.manager.StartProfiling(ctx, path, task)
-		if err != nil {
-			// 如果性能分析失败不要让请求失败
-			p.logger.Error("Failed to start profiling", map[string]interface{}{
-				"path":  path,
-				"error": err.Error(),
-			})
-			c.Next()
-			return
-		}
+		// 为请求协程打上pprof标签，使分析结果可以按路由、方法和分析ID过滤
+		profileID := core.NewProfileID()
+		labels := core.ProfileLabels(path, method, task.Path, profileID)
+		pprof.Do(c.Request.Context(), labels, func(ctx context.Context) {
+			// 开始性能分析
+			session, err := p.manager.StartProfiling(ctx, path, task)
+			if err != nil {
+				// 如果性能分析失败不要让请求失败
+				p.logger.Error("Failed to start profiling", map[string]interface{}{
+					"path":  path,
+					"error": err.Error(),
+				})
+				c.Next()
+				return
+			}
 
-		// 设置超时和执行
-		done := make(chan bool, 1)
-		timeout := time.Duration(task.Duration) * time.Second
-		if timeout == 0 {
-			timeout = 30 * time.Second
-		}
+			// 按需触发的请求通过响应头返回分析ID，用于定位保存的分析文件
+			if task.Source == core.SourceTrigger {
+				c.Header(core.ProfileIDHeader, profileID)
+			}
 
-		// 在协程中执行业务逻辑以控制超时
-		go func() {
+			// 业务逻辑在请求协程中同步执行，分析时长由会话自身控制。
+			// 处理函数panic时先保存分析结果，panic继续交给gin.Recovery处理
+			completed := false
 			defer func() {
-				if r := recover(); r != nil {
-					p.logger.Error("Request panic during profiling", map[string]interface{}{
-						"path":  path,
-						"panic": r,
-					})
+				outcome := core.PanicOutcome()
+				if completed {
+					outcome = core.RequestOutcome{
+						StatusCode: c.Writer.Status(),
+						HasErrors:  len(c.Errors) > 0,
+					}
 				}
-				done <- true
+				outcome.RequestPath = httpCtx.GetRequestPath()
+				p.stopProfiling(ctx, path, method, task, session, outcome)
 			}()
-			
+
+			c.Request = c.Request.WithContext(ctx)
 			c.Next()
-		}()
+			completed = true
+		})
+	}
+}
 
-		// 等待完成或超时
-		select {
-		case <-done:
-			// 请求正常完成
-		case <-time.After(timeout):
-			// 请求超时
-			p.logger.Warn("Request timed out during profiling", map[string]interface{}{
-				"path":    path,
-				"timeout": timeout.Seconds(),
-			})
-		}
+// stopProfiling 停止性能分析并保存结果
+func (p *Profiler) stopProfiling(ctx context.Context, path, method string, task core.ProfilingTask, session core.ProfileSession, outcome core.RequestOutcome) {
+	if !session.IsRunning() {
+		// 会话已到达分析时长，请求仍在继续执行
+		p.logger.Warn("Profiling deadline reached before request completed", map[string]interface{}{
+			"path":     path,
+			"duration": task.Duration,
+		})
+	}
 
-		// 停止性能分析并保存结果
-		result, err := p.manager.StopProfiling(ctx, path, method, task, session)
-		if err != nil {
-			p.logger.Error("Failed to stop profiling", map[string]interface{}{
-				"path":  path,
-				"error": err.Error(),
-			})
-		} else if result != nil && result.Success {
-			p.logger.Info("Profiling completed successfully", map[string]interface{}{
-				"path":        path,
-				"filename":    result.Filename,
-				"duration_ms": result.Duration.Milliseconds(),
-				"file_size":   result.FileSize,
-				"type":        result.ProfileType,
-			})
-		}
+	// 客户端断开时请求上下文会被取消，保存结果时不应受其影响
+	result, err := p.manager.StopProfiling(context.WithoutCancel(ctx), path, method, task, session, outcome)
+	if err != nil {
+		p.logger.Error("Failed to stop profiling", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+	} else if result != nil && result.Success && !result.Discarded {
+		p.logger.Info("Profiling completed successfully", map[string]interface{}{
+			"path":        path,
+			"profile_id":  result.ProfileID,
+			"status_code": result.StatusCode,
+			"filename":    result.Filename,
+			"duration_ms": result.Duration.Milliseconds(),
+			"file_size":   result.FileSize,
+			"type":        result.ProfileType,
+		})
 	}
-}
\ No newline at end of file
+}
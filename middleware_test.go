@@ -0,0 +1,62 @@
+package ginpprof
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTriggeredProfileIDFindsProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	opts := core.DefaultOptions()
+	opts.Trigger.Enabled = true
+	opts.Trigger.Secret = "s3cret"
+	p := newTestProfiler(t, New().WithOptions(opts).WithConfigProvider(&staticProvider{}))
+
+	engine := gin.New()
+	engine.Use(p.Middleware())
+	engine.GET("/api/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/profiles", p.ProfilesHandler())
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("X-Gin-Pprof", "goroutine")
+	req.Header.Set("X-Gin-Pprof-Token", "s3cret")
+	engine.ServeHTTP(recorder, req)
+	profileID := recorder.Header().Get(core.ProfileIDHeader)
+	if profileID == "" {
+		t.Fatalf("triggered response has no %s header", core.ProfileIDHeader)
+	}
+
+	// Another triggered request stores a second profile of the same route
+	other := httptest.NewRequest(http.MethodGet, "/api/users/2", nil)
+	other.Header = req.Header.Clone()
+	engine.ServeHTTP(httptest.NewRecorder(), other)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/profiles?profile_id="+profileID, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET /profiles = %d %s", recorder.Code, recorder.Body)
+		}
+		var response struct {
+			Profiles []core.ProfilingResult `json:"profiles"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Profiles) == 1 && response.Profiles[0].ProfileID == profileID && response.Profiles[0].RequestPath == "/api/users/1" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("profiles with ID %s: %+v", profileID, response.Profiles)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import "github.com/aclstack/gin-pprof/pkg/core"
+
+// setTaskDefaults fills in default values for fields that are not specified
+func setTaskDefaults(task *core.ProfilingTask) {
+	if task.Duration == 0 {
+		task.Duration = 30
+	}
+	if task.SampleRate == 0 {
+		task.SampleRate = 1
+	}
+	if task.ProfileType == "" {
+		task.ProfileType = "cpu"
+	}
+	// 设置默认方法 - 如果没有指定方法，默认为GET
+	if len(task.Methods) == 0 {
+		task.Methods = []string{"GET"}
+	}
+}
+
+// logQuarantined logs every task rejected by a config validation pass
+func logQuarantined(logger core.Logger, source string, quarantined []core.QuarantinedTask) {
+	for _, entry := range quarantined {
+		problems := make([]string, len(entry.Errors))
+		for i, fieldErr := range entry.Errors {
+			problems[i] = fieldErr.String()
+		}
+		logger.Warn("Invalid task quarantined", map[string]interface{}{
+			"source":        source,
+			"id":            entry.Task.ID,
+			"path":          entry.Task.Path,
+			"errors":        problems,
+			"kept_previous": entry.KeptPrevious,
+		})
+	}
+}
@@ -3,16 +3,27 @@ package config
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
 	"github.com/aclstack/gin-pprof/pkg/core"
 )
 
+// fileWatchDebounce is how long the file must be quiet before it is reloaded
+const fileWatchDebounce = 200 * time.Millisecond
+
 // FileConfig implements ConfigProvider interface using local YAML file
 type FileConfig struct {
-	filePath string
-	logger   core.Logger
+	filePath  string
+	logger    core.Logger
+	firstSeen *core.FirstSeenTracker
+	validator *core.TaskValidator
+
+	mu       sync.Mutex
+	watchers []*fsnotify.Watcher
 }
 
 // FileConfigFormat represents the structure of the config file
@@ -23,8 +34,10 @@ type FileConfigFormat struct {
 // NewFileConfig creates a new FileConfig
 func NewFileConfig(filePath string, logger core.Logger) core.ConfigProvider {
 	f := &FileConfig{
-		filePath: filePath,
-		logger:   logger,
+		filePath:  filePath,
+		logger:    logger,
+		firstSeen: core.NewFirstSeenTracker(),
+		validator: core.NewTaskValidator(),
 	}
 	
 	// Check if file exists, create example config if not
@@ -75,26 +88,25 @@ func (f *FileConfig) GetTasks(ctx context.Context) ([]core.ProfilingTask, error)
 		return nil, err
 	}
 
+	// Set default values if not specified
+	for i := range config.Profiles {
+		setTaskDefaults(&config.Profiles[i])
+		config.Profiles[i].Source = core.SourceFile
+	}
+
+	// Resolve relative ttl into expires_at, keeping the first-seen time across reloads
+	now := time.Now()
+	tasks := f.firstSeen.Resolve(config.Profiles, now)
+
+	// Quarantine invalid tasks, keeping the last valid version of a changed task
+	tasks, quarantined := f.validator.Filter(tasks, now)
+	logQuarantined(f.logger, f.filePath, quarantined)
+
 	// Filter out expired tasks
 	var validTasks []core.ProfilingTask
 	var expiredCount int
-	now := time.Now()
-	for _, task := range config.Profiles {
+	for _, task := range tasks {
 		if now.Before(task.ExpiresAt) {
-			// Set default values if not specified
-			if task.Duration == 0 {
-				task.Duration = 30
-			}
-			if task.SampleRate == 0 {
-				task.SampleRate = 1
-			}
-			if task.ProfileType == "" {
-				task.ProfileType = "cpu"
-			}
-			// 设置默认方法 - 如果没有指定方法，默认为GET
-			if len(task.Methods) == 0 {
-				task.Methods = []string{"GET"}
-			}
 			validTasks = append(validTasks, task)
 		} else {
 			expiredCount++
@@ -111,7 +123,7 @@ func (f *FileConfig) GetTasks(ctx context.Context) ([]core.ProfilingTask, error)
 			"total_tasks":   len(config.Profiles),
 			"expired_tasks": expiredCount,
 			"file":          f.filePath,
-			"action":        "Please update the expires_at timestamps in your config file",
+			"action":        "Please update the expires_at timestamps or use ttl in your config file",
 			"hint":          "All configured profiling tasks are past their expiration dates",
 		})
 	}
@@ -121,23 +133,145 @@ func (f *FileConfig) GetTasks(ctx context.Context) ([]core.ProfilingTask, error)
 		"total_tasks":  len(config.Profiles),
 		"valid_tasks":  len(validTasks),
 		"expired_tasks": expiredCount,
+		"rejected_tasks": len(quarantined),
 	})
 
 	return validTasks, nil
 }
 
-// Subscribe is not implemented for file config (file watching could be added later)
+// QuarantinedTasks returns the tasks rejected by the last load
+func (f *FileConfig) QuarantinedTasks() []core.QuarantinedTask {
+	return f.validator.QuarantinedTasks()
+}
+
+// Subscribe watches the config file and calls callback with the reloaded tasks.
+// The parent directory is watched so that editors replacing the file with an
+// atomic rename and Kubernetes ConfigMap symlink swaps are picked up. If the
+// changed file cannot be read or parsed, callback is not called and the last
+// good task set stays active.
 func (f *FileConfig) Subscribe(ctx context.Context, callback func([]core.ProfilingTask)) error {
-	// For file config, we don't support real-time subscription
-	// This could be enhanced with file watching in the future
-	f.logger.Info("File config subscription not implemented", map[string]interface{}{
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		f.logger.Error("Failed to create config file watcher", map[string]interface{}{
+			"file":  f.filePath,
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	configDir := filepath.Dir(f.filePath)
+	if err := watcher.Add(configDir); err != nil {
+		watcher.Close()
+		f.logger.Error("Failed to watch config directory", map[string]interface{}{
+			"file":      f.filePath,
+			"directory": configDir,
+			"error":     err.Error(),
+		})
+		return err
+	}
+
+	f.mu.Lock()
+	f.watchers = append(f.watchers, watcher)
+	f.mu.Unlock()
+
+	// Resolve the current target before watching so a swap right after
+	// Subscribe returns is still detected
+	realPath, _ := filepath.EvalSymlinks(f.filePath)
+	go f.watch(ctx, watcher, realPath, callback)
+
+	f.logger.Info("Watching config file for changes", map[string]interface{}{
 		"file": f.filePath,
 	})
 	return nil
 }
 
+// watch processes file system events until the watcher is closed
+func (f *FileConfig) watch(ctx context.Context, watcher *fsnotify.Watcher, realPath string, callback func([]core.ProfilingTask)) {
+	defer watcher.Close()
+
+	configFile := filepath.Clean(f.filePath)
+
+	debounce := time.NewTimer(fileWatchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	var reload <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			// ConfigMap volumes swap a "..data" symlink, so compare the
+			// resolved target in addition to the file name
+			currentPath, _ := filepath.EvalSymlinks(configFile)
+			fileChanged := filepath.Clean(event.Name) == configFile &&
+				event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0
+			targetChanged := currentPath != "" && currentPath != realPath
+			if !fileChanged && !targetChanged {
+				continue
+			}
+			realPath = currentPath
+
+			// Debounce bursts of events from a single save
+			if !debounce.Stop() {
+				select {
+				case <-debounce.C:
+				default:
+				}
+			}
+			debounce.Reset(fileWatchDebounce)
+			reload = debounce.C
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			f.logger.Warn("Config file watcher error", map[string]interface{}{
+				"file":  f.filePath,
+				"error": err.Error(),
+			})
+
+		case <-reload:
+			reload = nil
+			f.reload(ctx, callback)
+		}
+	}
+}
+
+// reload re-reads the config file and passes the tasks to callback
+func (f *FileConfig) reload(ctx context.Context, callback func([]core.ProfilingTask)) {
+	tasks, err := f.GetTasks(ctx)
+	if err != nil {
+		f.logger.Warn("Config reload failed, keeping last good tasks", map[string]interface{}{
+			"file":  f.filePath,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	f.logger.Info("Config file reloaded", map[string]interface{}{
+		"file":       f.filePath,
+		"task_count": len(tasks),
+	})
+	callback(tasks)
+}
+
 // Close closes the file config provider
 func (f *FileConfig) Close() error {
+	f.mu.Lock()
+	watchers := f.watchers
+	f.watchers = nil
+	f.mu.Unlock()
+
+	for _, watcher := range watchers {
+		watcher.Close()
+	}
+
 	f.logger.Info("File config provider closed", map[string]interface{}{
 		"file": f.filePath,
 	})
@@ -154,7 +288,7 @@ profiles:
   # 示例：用户详情端点的CPU分析（单个方法）
   # - path: "/api/users/:id"
   #   methods: ["GET"]       # 单个HTTP方法
-  #   expires_at: "2025-12-31T23:59:59Z"
+  #   ttl: 24h              # 首次加载后24小时过期，也可使用expires_at指定绝对时间
   #   duration: 10          # 分析10秒
   #   sample_rate: 1        # 每个请求都分析
   #   profile_type: "cpu"   # CPU分析
@@ -162,7 +296,7 @@ profiles:
   # 示例：多个方法的内存分析
   # - path: "/api/data/heavy"
   #   methods: ["POST", "PUT"]  # 多个HTTP方法数组
-  #   expires_at: "2025-12-31T23:59:59Z"
+  #   ttl: 24h
   #   duration: 15          # 分析15秒
   #   sample_rate: 5        # 每5个请求分析1次
   #   profile_type: "heap"  # 内存分析
@@ -170,15 +304,32 @@ profiles:
   # 示例：所有常用方法的协程分析
   # - path: "/api/concurrent/:operation"
   #   methods: ["*"]         # 通配符：匹配GET, POST, PUT, DELETE
-  #   expires_at: "2025-12-31T23:59:59Z" 
+  #   ttl: 24h
   #   duration: 20
   #   sample_rate: 2
   #   profile_type: "goroutine"
   
+  # 示例：随机采样1%的请求，每分钟最多10次，最多分析100次
+  # - path: "/api/feed"
+  #   ttl: 24h
+  #   sample_probability: 0.01
+  #   max_per_minute: 10
+  #   max_profiles: 100
+  #   max_captures: 20     # 保存20个分析文件后停止
+  #   max_total_bytes: 104857600  # 或保存的文件总计达到100MB后停止
+  
+  # 示例：每天UTC 02:00-03:00分析批量导入接口
+  # - path: "/api/import/:batch"
+  #   methods: ["POST"]
+  #   ttl: 24h
+  #   schedule: "0 2 * * *"
+  #   window: 1h
+  #   timezone: "UTC"
+  
   # 示例：默认行为（仅GET）
   # - path: "/api/health"
   #   # methods未指定 - 默认为GET
-  #   expires_at: "2025-12-31T23:59:59Z"
+  #   ttl: 24h
   #   duration: 5
   #   profile_type: "cpu"
 
@@ -190,9 +341,9 @@ profiles:
 #    - methods: ["POST", "PUT"] （多个方法）
 #    - methods: ["*"] （常用方法：GET, POST, PUT, DELETE）
 #    - 留空则默认为GET
-# 4. 设置合适的'expires_at'时间
+# 4. 设置合适的'ttl'或'expires_at'时间
 # 5. 根据需要调整'duration'和'sample_rate'
-# 6. 选择'profile_type'：cpu, heap或goroutine
+# 6. 选择'profile_type'：cpu, heap, heap_delta, allocs_delta, goroutine, mutex, block或trace
 #
 # 更多示例和文档：
 # https://github.com/aclstack/gin-pprof/blob/main/README.md
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/adapters/logger"
+	"github.com/aclstack/gin-pprof/pkg/core"
+)
+
+// reloadWait bounds how long a test waits for a reload callback
+const reloadWait = 5 * time.Second
+
+// configYAML returns a config file with one cpu task per path
+func configYAML(paths ...string) string {
+	var b strings.Builder
+	b.WriteString("profiles:\n")
+	for _, path := range paths {
+		fmt.Fprintf(&b, "  - path: %q\n    ttl: 1h\n    profile_type: cpu\n", path)
+	}
+	return b.String()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// subscribe subscribes to provider and returns a channel with the task paths
+// of every reload
+func subscribe(t *testing.T, provider core.ConfigProvider) <-chan []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		provider.Close()
+	})
+
+	reloads := make(chan []string, 16)
+	err := provider.Subscribe(ctx, func(tasks []core.ProfilingTask) {
+		paths := make([]string, len(tasks))
+		for i, task := range tasks {
+			paths[i] = task.Path
+		}
+		reloads <- paths
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reloads
+}
+
+// expectReload waits for the next reload and checks its task paths
+func expectReload(t *testing.T, reloads <-chan []string, want ...string) {
+	t.Helper()
+	select {
+	case got := <-reloads:
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("reloaded tasks = %v, want %v", got, want)
+		}
+	case <-time.After(reloadWait):
+		t.Fatalf("no reload, want %v", want)
+	}
+}
+
+// expectNoReload checks that no reload happens within d
+func expectNoReload(t *testing.T, reloads <-chan []string, d time.Duration) {
+	t.Helper()
+	select {
+	case got := <-reloads:
+		t.Fatalf("unexpected reload with tasks %v", got)
+	case <-time.After(d):
+	}
+}
+
+func TestFileConfigDebouncesBurstOfWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiling.yaml")
+	writeFile(t, path, configYAML("/initial"))
+	provider := NewFileConfig(path, logger.NewNoopLogger())
+	reloads := subscribe(t, provider)
+
+	// A burst of writes shorter than the debounce interval is one reload
+	for i := 1; i <= 5; i++ {
+		writeFile(t, path, configYAML(fmt.Sprintf("/burst/%d", i)))
+		time.Sleep(fileWatchDebounce / 10)
+	}
+
+	expectReload(t, reloads, "/burst/5")
+	expectNoReload(t, reloads, 2*fileWatchDebounce)
+}
+
+func TestFileConfigAtomicRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profiling.yaml")
+	writeFile(t, path, configYAML("/initial"))
+	provider := NewFileConfig(path, logger.NewNoopLogger())
+	reloads := subscribe(t, provider)
+
+	// Editors write a temporary file and rename it over the config file
+	tmp := filepath.Join(dir, ".profiling.yaml.swp")
+	writeFile(t, tmp, configYAML("/renamed"))
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	expectReload(t, reloads, "/renamed")
+}
+
+func TestFileConfigConfigMapSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+
+	// Layout of a Kubernetes ConfigMap volume:
+	//   profiling.yaml -> ..data/profiling.yaml
+	//   ..data -> ..v1
+	//   ..v1/profiling.yaml
+	writeVersion := func(version, content string) {
+		if err := os.Mkdir(filepath.Join(dir, version), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, version, "profiling.yaml"), content)
+	}
+	writeVersion("..v1", configYAML("/v1"))
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profiling.yaml")
+	if err := os.Symlink(filepath.Join("..data", "profiling.yaml"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewFileConfig(path, logger.NewNoopLogger())
+	reloads := subscribe(t, provider)
+
+	// The kubelet writes a new version and atomically swaps ..data; the
+	// config file name itself never changes
+	writeVersion("..v2", configYAML("/v2"))
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink("..v2", tmpLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "..v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	expectReload(t, reloads, "/v2")
+}
+
+func TestFileConfigKeepsLastGoodTasksOnParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiling.yaml")
+	writeFile(t, path, configYAML("/good"))
+	provider := NewFileConfig(path, logger.NewNoopLogger())
+	reloads := subscribe(t, provider)
+
+	// A file that fails to parse does not replace the active tasks
+	writeFile(t, path, "profiles:\n  - path: [unterminated\n")
+	expectNoReload(t, reloads, 3*fileWatchDebounce)
+	if _, err := provider.GetTasks(context.Background()); err == nil {
+		t.Fatal("GetTasks accepted an unparsable config file")
+	}
+
+	// The next good version is picked up again
+	writeFile(t, path, configYAML("/fixed"))
+	expectReload(t, reloads, "/fixed")
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+)
+
+// ConflictStrategy decides which source wins when several sources define a
+// task with the same ID
+type ConflictStrategy string
+
+const (
+	// ConflictSourcePriority keeps the task from the source with the highest
+	// priority; on equal priority the source listed later wins
+	ConflictSourcePriority ConflictStrategy = "source_priority"
+	// ConflictLastWriterWins keeps the task from the source that most recently
+	// added or changed it
+	ConflictLastWriterWins ConflictStrategy = "last_writer_wins"
+)
+
+// ProviderSource is a named task source of a MultiConfigProvider
+type ProviderSource struct {
+	// Name tags every task of this source (e.g. "baseline", "nacos")
+	Name string
+	// Provider supplies the tasks
+	Provider core.ConfigProvider
+	// Priority is used by ConflictSourcePriority, higher wins
+	Priority int
+}
+
+// MultiConfigProvider implements ConfigProvider by merging the tasks of
+// several providers. Tasks are matched by ID (generated when missing) and
+// conflicts are resolved with the configured strategy.
+type MultiConfigProvider struct {
+	sources  []ProviderSource
+	strategy ConflictStrategy
+	logger   core.Logger
+
+	mu         sync.Mutex
+	tasks      [][]core.ProfilingTask
+	loaded     []bool
+	writes     map[taskWriteKey]uint64
+	seq        uint64
+	callbacks  []func([]core.ProfilingTask)
+	subscribed bool
+
+	// notifyMu keeps callbacks in update order
+	notifyMu sync.Mutex
+}
+
+// taskWriteKey identifies a task ID within a source
+type taskWriteKey struct {
+	id     string
+	source int
+}
+
+// NewMultiConfigProvider creates a provider that merges the given sources
+func NewMultiConfigProvider(strategy ConflictStrategy, logger core.Logger, sources ...ProviderSource) *MultiConfigProvider {
+	if strategy == "" {
+		strategy = ConflictSourcePriority
+	}
+
+	return &MultiConfigProvider{
+		sources:  sources,
+		strategy: strategy,
+		logger:   logger,
+		tasks:    make([][]core.ProfilingTask, len(sources)),
+		loaded:   make([]bool, len(sources)),
+		writes:   make(map[taskWriteKey]uint64),
+	}
+}
+
+// GetTasks loads the tasks of every source and returns the merged set. A
+// source that fails keeps its previously loaded tasks; an error is only
+// returned when no source could be loaded.
+func (m *MultiConfigProvider) GetTasks(ctx context.Context) ([]core.ProfilingTask, error) {
+	var firstErr error
+	succeeded := 0
+
+	for i, source := range m.sources {
+		tasks, err := source.Provider.GetTasks(ctx)
+		if err != nil {
+			m.logger.Error("Failed to load tasks from source", map[string]interface{}{
+				"source": source.Name,
+				"error":  err.Error(),
+			})
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		succeeded++
+
+		m.mu.Lock()
+		m.setSourceTasksLocked(i, tasks)
+		m.mu.Unlock()
+	}
+
+	if succeeded == 0 && len(m.sources) > 0 {
+		return nil, firstErr
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mergeLocked(), nil
+}
+
+// Subscribe subscribes to every source and calls callback with the merged
+// tasks whenever one of them changes
+func (m *MultiConfigProvider) Subscribe(ctx context.Context, callback func([]core.ProfilingTask)) error {
+	m.mu.Lock()
+	m.callbacks = append(m.callbacks, callback)
+	if m.subscribed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.subscribed = true
+	m.mu.Unlock()
+
+	var firstErr error
+	for i, source := range m.sources {
+		index := i
+		err := source.Provider.Subscribe(ctx, func(tasks []core.ProfilingTask) {
+			m.mu.Lock()
+			m.setSourceTasksLocked(index, tasks)
+			m.mu.Unlock()
+			m.notify()
+		})
+		if err != nil {
+			m.logger.Error("Failed to subscribe to source", map[string]interface{}{
+				"source": source.Name,
+				"error":  err.Error(),
+			})
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Close closes every source and returns the first error
+func (m *MultiConfigProvider) Close() error {
+	var firstErr error
+	for _, source := range m.sources {
+		if err := source.Provider.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// QuarantinedTasks returns the tasks rejected by the sources that report
+// them, tagged with the source name
+func (m *MultiConfigProvider) QuarantinedTasks() []core.QuarantinedTask {
+	var quarantined []core.QuarantinedTask
+	for _, source := range m.sources {
+		reporter, ok := source.Provider.(core.TaskQuarantine)
+		if !ok {
+			continue
+		}
+		for _, entry := range reporter.QuarantinedTasks() {
+			if source.Name != "" {
+				entry.Task.Source = source.Name
+			}
+			quarantined = append(quarantined, entry)
+		}
+	}
+	return quarantined
+}
+
+// setSourceTasksLocked stores the tasks of a source and records which task
+// IDs it added or changed, for last-writer-wins
+func (m *MultiConfigProvider) setSourceTasksLocked(index int, tasks []core.ProfilingTask) {
+	previous := make(map[string]core.ProfilingTask, len(m.tasks[index]))
+	for _, task := range m.tasks[index] {
+		previous[taskKey(task)] = task
+	}
+
+	m.seq++
+	current := make(map[string]bool, len(tasks))
+	tagged := make([]core.ProfilingTask, len(tasks))
+	for i, task := range tasks {
+		if name := m.sources[index].Name; name != "" {
+			task.Source = name
+		}
+		tagged[i] = task
+
+		id := taskKey(task)
+		current[id] = true
+		if old, exists := previous[id]; !exists || !m.loaded[index] || !reflect.DeepEqual(old, task) {
+			m.writes[taskWriteKey{id: id, source: index}] = m.seq
+		}
+	}
+
+	// Forget write times of tasks the source no longer defines
+	for id := range previous {
+		if !current[id] {
+			delete(m.writes, taskWriteKey{id: id, source: index})
+		}
+	}
+
+	m.tasks[index] = tagged
+	m.loaded[index] = true
+}
+
+// mergeLocked merges the tasks of all sources, keeping for every task ID only
+// the tasks of the winning source
+func (m *MultiConfigProvider) mergeLocked() []core.ProfilingTask {
+	winners := make(map[string]int)
+	for i, tasks := range m.tasks {
+		for _, task := range tasks {
+			id := taskKey(task)
+			winner, exists := winners[id]
+			if !exists || m.beats(id, i, winner) {
+				if exists && winner != i {
+					m.logger.Debug("Task conflict resolved", map[string]interface{}{
+						"id":       id,
+						"winner":   m.sources[i].Name,
+						"loser":    m.sources[winner].Name,
+						"strategy": string(m.strategy),
+					})
+				}
+				winners[id] = i
+			}
+		}
+	}
+
+	var merged []core.ProfilingTask
+	for i, tasks := range m.tasks {
+		for _, task := range tasks {
+			if winners[taskKey(task)] == i {
+				merged = append(merged, task)
+			}
+		}
+	}
+	return merged
+}
+
+// beats reports whether source a wins task id over source b. Ties go to the
+// source listed later.
+func (m *MultiConfigProvider) beats(id string, a, b int) bool {
+	if a == b {
+		return false
+	}
+
+	switch m.strategy {
+	case ConflictLastWriterWins:
+		wa := m.writes[taskWriteKey{id: id, source: a}]
+		wb := m.writes[taskWriteKey{id: id, source: b}]
+		if wa != wb {
+			return wa > wb
+		}
+	default:
+		pa, pb := m.sources[a].Priority, m.sources[b].Priority
+		if pa != pb {
+			return pa > pb
+		}
+	}
+	return a > b
+}
+
+// notify calls the subscribed callbacks with the merged tasks
+func (m *MultiConfigProvider) notify() {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	m.mu.Lock()
+	tasks := m.mergeLocked()
+	callbacks := append([]func([]core.ProfilingTask){}, m.callbacks...)
+	m.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(tasks)
+	}
+}
+
+// taskKey returns the ID used to detect conflicts between sources
+func taskKey(task core.ProfilingTask) string {
+	if task.ID != "" {
+		return task.ID
+	}
+	return task.GenerateID()
+}
@@ -24,6 +24,8 @@ type NacosConfig struct {
 	password   string
 	client     config_client.IConfigClient
 	logger     core.Logger
+	firstSeen  *core.FirstSeenTracker
+	validator  *core.TaskValidator
 }
 
 // NacosOptions contains options for Nacos configuration
@@ -46,6 +48,8 @@ func NewNacosConfig(opts NacosOptions, logger core.Logger) (core.ConfigProvider,
 		username:   opts.Username,
 		password:   opts.Password,
 		logger:     logger,
+		firstSeen:  core.NewFirstSeenTracker(),
+		validator:  core.NewTaskValidator(),
 	}
 
 	// Set defaults
@@ -185,6 +189,11 @@ func (n *NacosConfig) Close() error {
 	return nil
 }
 
+// QuarantinedTasks returns the tasks rejected by the last config load
+func (n *NacosConfig) QuarantinedTasks() []core.QuarantinedTask {
+	return n.validator.QuarantinedTasks()
+}
+
 // parseConfig parses the configuration data
 func (n *NacosConfig) parseConfig(data string) ([]core.ProfilingTask, error) {
 	if data == "" {
@@ -215,25 +224,19 @@ func (n *NacosConfig) parseConfig(data string) ([]core.ProfilingTask, error) {
 
 // processEnhancedConfig processes enhanced format configuration
 func (n *NacosConfig) processEnhancedConfig(profiles []core.ProfilingTask) []core.ProfilingTask {
-	var validTasks []core.ProfilingTask
-	now := time.Now()
+	// Set default values
+	for i := range profiles {
+		setTaskDefaults(&profiles[i])
+		profiles[i].Source = core.SourceNacos
+	}
 
-	for _, profile := range profiles {
-		// Set default values
-		if profile.Duration == 0 {
-			profile.Duration = 30
-		}
-		if profile.SampleRate == 0 {
-			profile.SampleRate = 1
-		}
-		if profile.ProfileType == "" {
-			profile.ProfileType = "cpu"
-		}
-		// Set default method - only set if both method and methods are empty
-		if profile.Method == "" && len(profile.Methods) == 0 {
-			profile.Method = "GET"
-		}
+	// Resolve relative ttl into expires_at, keeping the first-seen time across reloads
+	now := time.Now()
+	resolved, quarantined := n.validator.Filter(n.firstSeen.Resolve(profiles, now), now)
+	logQuarantined(n.logger, n.dataID, quarantined)
 
+	var validTasks []core.ProfilingTask
+	for _, profile := range resolved {
 		// Check if expired
 		if now.After(profile.ExpiresAt) {
 			n.logger.Warn("Task expired", map[string]interface{}{
@@ -247,52 +250,67 @@ func (n *NacosConfig) processEnhancedConfig(profiles []core.ProfilingTask) []cor
 	}
 
 	n.logger.Info("Enhanced config processed", map[string]interface{}{
-		"total_tasks": len(profiles),
-		"valid_tasks": len(validTasks),
+		"total_tasks":    len(profiles),
+		"valid_tasks":    len(validTasks),
+		"rejected_tasks": len(quarantined),
 	})
 
 	return validTasks
 }
 
-// processSimpleConfig processes simple format configuration
+// processSimpleConfig processes simple format configuration. Each value is
+// either an RFC3339 expiry time or a relative ttl such as "30m".
 func (n *NacosConfig) processSimpleConfig(rawTasks map[string]string) []core.ProfilingTask {
-	var validTasks []core.ProfilingTask
-	now := time.Now()
+	var tasks []core.ProfilingTask
+	for path, value := range rawTasks {
+		task := core.ProfilingTask{
+			Path:        path,
+			Methods:     []string{"GET"}, // Default GET method
+			Duration:    30,              // Default 30 seconds
+			SampleRate:  1,               // Default no sampling
+			ProfileType: "cpu",           // Default CPU profiling
+			Source:      core.SourceNacos,
+		}
 
-	for path, expiresAtStr := range rawTasks {
-		expiresAt, err := time.Parse(time.RFC3339, expiresAtStr)
-		if err != nil {
+		if expiresAt, err := time.Parse(time.RFC3339, value); err == nil {
+			task.ExpiresAt = expiresAt
+		} else if ttl, ttlErr := time.ParseDuration(value); ttlErr == nil && ttl > 0 {
+			task.TTL = ttl
+		} else {
 			n.logger.Warn("Invalid time format", map[string]interface{}{
 				"path":       path,
-				"expires_at": expiresAtStr,
+				"expires_at": value,
 				"error":      err.Error(),
 			})
 			continue
 		}
 
+		tasks = append(tasks, task)
+	}
+
+	// Resolve relative ttl into expires_at, keeping the first-seen time across reloads
+	now := time.Now()
+	resolved, quarantined := n.validator.Filter(n.firstSeen.Resolve(tasks, now), now)
+	logQuarantined(n.logger, n.dataID, quarantined)
+
+	var validTasks []core.ProfilingTask
+	for _, task := range resolved {
 		// Check if expired
-		if now.After(expiresAt) {
+		if now.After(task.ExpiresAt) {
 			n.logger.Warn("Task expired", map[string]interface{}{
-				"path":       path,
-				"expires_at": expiresAtStr,
+				"path":       task.Path,
+				"expires_at": task.ExpiresAt.Format(time.RFC3339),
 			})
 			continue
 		}
 
-		validTasks = append(validTasks, core.ProfilingTask{
-			Path:        path,
-			Method:      "GET",  // Default GET method
-			Methods:     nil,    // Empty methods array
-			ExpiresAt:   expiresAt,
-			Duration:    30,     // Default 30 seconds
-			SampleRate:  1,      // Default no sampling
-			ProfileType: "cpu",  // Default CPU profiling
-		})
+		validTasks = append(validTasks, task)
 	}
 
 	n.logger.Info("Simple config processed", map[string]interface{}{
-		"total_tasks": len(rawTasks),
-		"valid_tasks": len(validTasks),
+		"total_tasks":    len(rawTasks),
+		"valid_tasks":    len(validTasks),
+		"rejected_tasks": len(quarantined),
 	})
 
 	return validTasks
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrTaskExists is returned when creating a runtime task whose ID is taken
+	ErrTaskExists = errors.New("runtime task already exists")
+	// ErrTaskNotFound is returned when updating or deleting an unknown runtime task
+	ErrTaskNotFound = errors.New("runtime task not found")
+)
+
+// RuntimeConfig implements ConfigProvider for tasks created at runtime through
+// the admin API. Tasks are kept in memory and, when a persist path is set,
+// written to a YAML file in the FileConfig format so they survive restarts.
+type RuntimeConfig struct {
+	persistPath string
+	logger      core.Logger
+
+	mu        sync.Mutex
+	tasks     map[string]core.ProfilingTask
+	callbacks []func([]core.ProfilingTask)
+
+	// notifyMu keeps callbacks in commit order
+	notifyMu sync.Mutex
+}
+
+// NewRuntimeConfig creates a new RuntimeConfig. An empty persistPath keeps
+// runtime tasks in memory only.
+func NewRuntimeConfig(persistPath string, logger core.Logger) *RuntimeConfig {
+	r := &RuntimeConfig{
+		persistPath: persistPath,
+		logger:      logger,
+		tasks:       make(map[string]core.ProfilingTask),
+	}
+
+	if persistPath != "" {
+		if err := r.load(); err != nil {
+			logger.Error("Failed to load persisted runtime tasks", map[string]interface{}{
+				"file":  persistPath,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return r
+}
+
+// GetTasks returns the runtime tasks that have not expired, ordered by ID
+func (r *RuntimeConfig) GetTasks(ctx context.Context) ([]core.ProfilingTask, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.listLocked(time.Now()), nil
+}
+
+// Subscribe calls callback whenever a runtime task is created, updated or deleted
+func (r *RuntimeConfig) Subscribe(ctx context.Context, callback func([]core.ProfilingTask)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.callbacks = append(r.callbacks, callback)
+	return nil
+}
+
+// Close closes the runtime config provider
+func (r *RuntimeConfig) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.callbacks = nil
+	return nil
+}
+
+// Create validates and adds a runtime task. A missing ID is generated, and a
+// ttl is resolved against the current time.
+func (r *RuntimeConfig) Create(task core.ProfilingTask) (core.ProfilingTask, error) {
+	task, err := r.normalize(task)
+	if err != nil {
+		return core.ProfilingTask{}, err
+	}
+
+	r.mu.Lock()
+	if _, exists := r.tasks[task.ID]; exists {
+		r.mu.Unlock()
+		return core.ProfilingTask{}, ErrTaskExists
+	}
+	if err := r.commitLocked(task.ID, &task); err != nil {
+		r.mu.Unlock()
+		return core.ProfilingTask{}, err
+	}
+	r.mu.Unlock()
+
+	r.logger.Info("Runtime task created", map[string]interface{}{
+		"id":   task.ID,
+		"path": task.Path,
+		"type": task.ProfileType,
+	})
+	r.notify()
+	return task, nil
+}
+
+// Update validates and replaces the runtime task with the given ID
+func (r *RuntimeConfig) Update(id string, task core.ProfilingTask) (core.ProfilingTask, error) {
+	task.ID = id
+	task, err := r.normalize(task)
+	if err != nil {
+		return core.ProfilingTask{}, err
+	}
+
+	r.mu.Lock()
+	if _, exists := r.tasks[id]; !exists {
+		r.mu.Unlock()
+		return core.ProfilingTask{}, ErrTaskNotFound
+	}
+	if err := r.commitLocked(id, &task); err != nil {
+		r.mu.Unlock()
+		return core.ProfilingTask{}, err
+	}
+	r.mu.Unlock()
+
+	r.logger.Info("Runtime task updated", map[string]interface{}{
+		"id":   id,
+		"path": task.Path,
+		"type": task.ProfileType,
+	})
+	r.notify()
+	return task, nil
+}
+
+// Delete cancels the runtime task with the given ID
+func (r *RuntimeConfig) Delete(id string) error {
+	r.mu.Lock()
+	if _, exists := r.tasks[id]; !exists {
+		r.mu.Unlock()
+		return ErrTaskNotFound
+	}
+	if err := r.commitLocked(id, nil); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	r.mu.Unlock()
+
+	r.logger.Info("Runtime task deleted", map[string]interface{}{
+		"id": id,
+	})
+	r.notify()
+	return nil
+}
+
+// normalize applies defaults, resolves ttl and validates a runtime task
+func (r *RuntimeConfig) normalize(task core.ProfilingTask) (core.ProfilingTask, error) {
+	setTaskDefaults(&task)
+	task.Source = core.SourceRuntime
+
+	if task.TTL > 0 {
+		start := time.Now()
+		if task.StartsAt.After(start) {
+			start = task.StartsAt
+		}
+		if expiresAt := start.Add(task.TTL); task.ExpiresAt.IsZero() || expiresAt.Before(task.ExpiresAt) {
+			task.ExpiresAt = expiresAt
+		}
+	}
+
+	if err := task.Validate(); err != nil {
+		return core.ProfilingTask{}, err
+	}
+	if task.ID == "" {
+		task.ID = task.GenerateID()
+	}
+	return task, nil
+}
+
+// commitLocked sets (or deletes when task is nil) a task and persists the
+// result. The in-memory set is only changed if persisting succeeds.
+func (r *RuntimeConfig) commitLocked(id string, task *core.ProfilingTask) error {
+	tasks := make(map[string]core.ProfilingTask, len(r.tasks)+1)
+	for k, v := range r.tasks {
+		tasks[k] = v
+	}
+	if task != nil {
+		tasks[id] = *task
+	} else {
+		delete(tasks, id)
+	}
+
+	if err := r.persist(tasks); err != nil {
+		r.logger.Error("Failed to persist runtime tasks", map[string]interface{}{
+			"file":  r.persistPath,
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	r.tasks = tasks
+	return nil
+}
+
+// listLocked returns the tasks that have not expired, ordered by ID
+func (r *RuntimeConfig) listLocked(now time.Time) []core.ProfilingTask {
+	tasks := make([]core.ProfilingTask, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		if now.Before(task.ExpiresAt) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+// notify calls the subscribed callbacks with the current tasks
+func (r *RuntimeConfig) notify() {
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+
+	r.mu.Lock()
+	tasks := r.listLocked(time.Now())
+	callbacks := append([]func([]core.ProfilingTask){}, r.callbacks...)
+	r.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(tasks)
+	}
+}
+
+// load reads persisted runtime tasks, skipping expired ones
+func (r *RuntimeConfig) load() error {
+	data, err := os.ReadFile(r.persistPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var config FileConfigFormat
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, task := range config.Profiles {
+		if task.ID == "" || !now.Before(task.ExpiresAt) {
+			continue
+		}
+		task.Source = core.SourceRuntime
+		r.tasks[task.ID] = task
+	}
+
+	r.logger.Info("Runtime tasks loaded", map[string]interface{}{
+		"file":       r.persistPath,
+		"task_count": len(r.tasks),
+	})
+	return nil
+}
+
+// persist writes tasks to the persist path through a temporary file and an
+// atomic rename, so a crash never leaves a truncated file behind
+func (r *RuntimeConfig) persist(tasks map[string]core.ProfilingTask) error {
+	if r.persistPath == "" {
+		return nil
+	}
+
+	config := FileConfigFormat{Profiles: make([]core.ProfilingTask, 0, len(tasks))}
+	for _, task := range tasks {
+		config.Profiles = append(config.Profiles, task)
+	}
+	sort.Slice(config.Profiles, func(i, j int) bool {
+		return config.Profiles[i].ID < config.Profiles[j].ID
+	})
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(r.persistPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(r.persistPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.persistPath)
+}
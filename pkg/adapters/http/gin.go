@@ -62,6 +62,11 @@ func (g *GinContext) GetRequestPath() string {
 	return g.ctx.Request.URL.Path
 }
 
+// GetQuery returns the value of a query parameter
+func (g *GinContext) GetQuery(key string) string {
+	return g.ctx.Query(key)
+}
+
 // GinPathMatcher implements path matching for Gin routes
 type GinPathMatcher struct{}
 
@@ -70,48 +75,95 @@ func NewGinPathMatcher() core.PathMatcher {
 	return &GinPathMatcher{}
 }
 
-// Match checks if actual path matches the gin route template
+// Match checks if actual path matches the gin route template. Besides
+// ":param" and "*" segments, a trailing "*name" catch-all matches the rest of
+// the path as in gin, and a "regex:" prefix matches the path with a regular
+// expression. The manager matches tasks against the route template
+// (c.FullPath()), so on parameterized routes a regex sees "/orders/:id"
+// rather than the request path "/orders/17".
 func (g *GinPathMatcher) Match(template, actual string) bool {
-	templateParts := strings.Split(strings.Trim(template, "/"), "/")
-	actualParts := strings.Split(strings.Trim(actual, "/"), "/")
-
-	if len(templateParts) != len(actualParts) {
-		return false
+	if pattern, ok := regexPattern(template); ok {
+		return matchRegex(pattern, actual, nil)
 	}
+	return matchGinTemplate(template, actual, nil)
+}
 
-	for i, templatePart := range templateParts {
-		if strings.HasPrefix(templatePart, ":") || templatePart == "*" {
-			// This is a parameter or wildcard, it matches anything
-			continue
-		}
-		if templatePart != actualParts[i] {
-			return false
+// ExtractParams extracts parameters from actual path using gin route template.
+// Catch-all values keep their leading slash (e.g. "/css/app.css"), regex
+// templates return their named groups.
+func (g *GinPathMatcher) ExtractParams(template, actual string) map[string]string {
+	params := make(map[string]string)
+
+	if pattern, ok := regexPattern(template); ok {
+		if !matchRegex(pattern, actual, params) {
+			return make(map[string]string)
 		}
+		return params
 	}
 
-	return true
+	if !matchGinTemplate(template, actual, params) {
+		return make(map[string]string)
+	}
+	return params
 }
 
-// ExtractParams extracts parameters from actual path using gin route template
-func (g *GinPathMatcher) ExtractParams(template, actual string) map[string]string {
-	params := make(map[string]string)
-	
+// matchGinTemplate matches actual against a gin route template and collects
+// parameter values into params when it is not nil
+func matchGinTemplate(template, actual string, params map[string]string) bool {
 	templateParts := strings.Split(strings.Trim(template, "/"), "/")
 	actualParts := strings.Split(strings.Trim(actual, "/"), "/")
 
-	if len(templateParts) != len(actualParts) {
-		return params
-	}
-
 	for i, templatePart := range templateParts {
-		if strings.HasPrefix(templatePart, ":") {
-			// Extract parameter name (remove the ':')
-			paramName := templatePart[1:]
-			params[paramName] = actualParts[i]
+		if isCatchAll(templatePart) {
+			// Catch-all parameters are only valid as the last segment
+			if i != len(templateParts)-1 {
+				return false
+			}
+			// As in gin, "/static/*filepath" matches "/static/" but not "/static"
+			if i >= len(actualParts) && !strings.HasSuffix(actual, "/") {
+				return false
+			}
+			if params != nil {
+				params[templatePart[1:]] = catchAllValue(actual, actualParts, i)
+			}
+			return true
+		}
+
+		if i >= len(actualParts) {
+			return false
+		}
+
+		switch {
+		case strings.HasPrefix(templatePart, ":"):
+			if params != nil {
+				params[templatePart[1:]] = actualParts[i]
+			}
+		case templatePart == "*":
+			// Wildcard matches any single segment
+		case templatePart != actualParts[i]:
+			return false
 		}
 	}
 
-	return params
+	return len(templateParts) == len(actualParts)
+}
+
+// isCatchAll reports whether a template segment is a gin catch-all ("*name")
+func isCatchAll(segment string) bool {
+	return len(segment) > 1 && segment[0] == '*' && segment[1] != '*'
+}
+
+// catchAllValue returns the catch-all value starting at segment index i. Like
+// gin, the value keeps its leading slash and a trailing slash if present.
+func catchAllValue(actual string, actualParts []string, i int) string {
+	if i >= len(actualParts) || (i == 0 && actualParts[0] == "") {
+		return "/"
+	}
+	value := "/" + strings.Join(actualParts[i:], "/")
+	if strings.HasSuffix(actual, "/") {
+		value += "/"
+	}
+	return value
 }
 
 // keyToString converts interface{} key to string for gin context
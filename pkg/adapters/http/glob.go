@@ -0,0 +1,165 @@
+package http
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+)
+
+// GlobPathMatcher matches task paths as globs. Each segment is matched with
+// path.Match ("*", "?", "[a-z]"), "**" matches zero or more segments and
+// ":param" matches any single segment. A last segment of "*" followed by a
+// name, such as gin's "*filepath", is a catch-all parameter matching the
+// remaining segments; its value has a leading "/" as in gin
+// ("/static/*filepath" extracts "/css/app.css" from "/static/css/app.css").
+// "*.css" and other segments with glob characters after the "*" stay globs.
+// Trailing slashes are ignored, so unlike gin "/static/*filepath" matches
+// "/static" and yields "/" as the value.
+//
+// A "regex:" prefix is supported as in GinPathMatcher. Tasks are matched
+// against the gin route template (c.FullPath()), not the request path, so
+// a regex sees "/orders/:id" rather than "/orders/17" on parameterized
+// routes, and its named groups are only extracted when ExtractParams is
+// called with a concrete path.
+//
+// Example: "/api/v1/reports/**" matches every route under /api/v1/reports.
+type GlobPathMatcher struct{}
+
+// NewGlobPathMatcher creates a new GlobPathMatcher
+func NewGlobPathMatcher() core.PathMatcher {
+	return &GlobPathMatcher{}
+}
+
+// Match checks if actual path matches the glob pattern
+func (g *GlobPathMatcher) Match(pattern, actual string) bool {
+	if expr, ok := regexPattern(pattern); ok {
+		return matchRegex(expr, actual, nil)
+	}
+	return matchGlobSegments(splitSegments(pattern), splitSegments(actual), nil)
+}
+
+// ExtractParams extracts ":param" values, or named groups of a regex pattern
+func (g *GlobPathMatcher) ExtractParams(pattern, actual string) map[string]string {
+	params := make(map[string]string)
+
+	if expr, ok := regexPattern(pattern); ok {
+		if !matchRegex(expr, actual, params) {
+			return make(map[string]string)
+		}
+		return params
+	}
+
+	if !matchGlobSegments(splitSegments(pattern), splitSegments(actual), params) {
+		return make(map[string]string)
+	}
+	return params
+}
+
+// matchGlobSegments matches path segments against glob segments
+func matchGlobSegments(pattern, actual []string, params map[string]string) bool {
+	if len(pattern) == 0 {
+		return len(actual) == 0
+	}
+
+	if pattern[0] == "**" {
+		// Try the shortest expansion first so later segments decide the match
+		for i := 0; i <= len(actual); i++ {
+			if matchGlobSegments(pattern[1:], actual[i:], params) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if name, ok := catchAllName(pattern); ok {
+		if params != nil {
+			params[name] = "/" + strings.Join(actual, "/")
+		}
+		return true
+	}
+
+	if len(actual) == 0 {
+		return false
+	}
+
+	if strings.HasPrefix(pattern[0], ":") {
+		if !matchGlobSegments(pattern[1:], actual[1:], params) {
+			return false
+		}
+		if params != nil {
+			params[pattern[0][1:]] = actual[0]
+		}
+		return true
+	}
+
+	if matched, err := path.Match(pattern[0], actual[0]); err != nil || !matched {
+		return false
+	}
+	return matchGlobSegments(pattern[1:], actual[1:], params)
+}
+
+// catchAllPattern matches the name of a gin catch-all parameter
+var catchAllPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// catchAllName returns the parameter name when the only pattern segment
+// left is a gin catch-all such as "*filepath"
+func catchAllName(pattern []string) (string, bool) {
+	if len(pattern) != 1 || !strings.HasPrefix(pattern[0], "*") {
+		return "", false
+	}
+	name := pattern[0][1:]
+	return name, catchAllPattern.MatchString(name)
+}
+
+// splitSegments splits a path into segments, the root path has none
+func splitSegments(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// regexCache caches compiled regex patterns, invalid patterns are stored as nil
+var regexCache sync.Map
+
+// regexPattern returns the expression of a "regex:" path pattern
+func regexPattern(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, core.RegexPathPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(pattern, core.RegexPathPrefix), true
+}
+
+// matchRegex matches actual against expr and collects named groups into
+// params when it is not nil. Invalid expressions never match.
+func matchRegex(expr, actual string, params map[string]string) bool {
+	var re *regexp.Regexp
+	if cached, ok := regexCache.Load(expr); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		// regexp.Compile returns nil on error, which is cached as well
+		re, _ = regexp.Compile(expr)
+		regexCache.Store(expr, re)
+	}
+	if re == nil {
+		return false
+	}
+
+	match := re.FindStringSubmatch(actual)
+	if match == nil {
+		return false
+	}
+
+	if params != nil {
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				params[name] = match[i]
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+	"github.com/gin-gonic/gin"
+)
+
+// matcherCase is a path matcher table entry; params is nil when the path
+// must not match
+type matcherCase struct {
+	pattern string
+	path    string
+	params  map[string]string
+}
+
+func runMatcherCases(t *testing.T, matcher core.PathMatcher, cases []matcherCase) {
+	t.Helper()
+	for _, tt := range cases {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			want := tt.params != nil
+			if got := matcher.Match(tt.pattern, tt.path); got != want {
+				t.Fatalf("Match = %v, want %v", got, want)
+			}
+
+			params := matcher.ExtractParams(tt.pattern, tt.path)
+			wantParams := tt.params
+			if wantParams == nil {
+				wantParams = map[string]string{}
+			}
+			if !reflect.DeepEqual(params, wantParams) {
+				t.Fatalf("ExtractParams = %v, want %v", params, wantParams)
+			}
+		})
+	}
+}
+
+func TestGinPathMatcher(t *testing.T) {
+	runMatcherCases(t, NewGinPathMatcher(), []matcherCase{
+		{"/api/users", "/api/users", map[string]string{}},
+		{"/api/users", "/api/users/1", nil},
+		{"/api/users/:id", "/api/users/42", map[string]string{"id": "42"}},
+		{"/api/users/:id", "/api/users", nil},
+		{"/api/users/:id/posts/:post", "/api/users/1/posts/2", map[string]string{"id": "1", "post": "2"}},
+		{"/api/*", "/api/users", map[string]string{}},
+		{"/api/*", "/api/users/1", nil},
+
+		// Catch-all keeps the leading slash as in gin
+		{"/static/*filepath", "/static/css/app.css", map[string]string{"filepath": "/css/app.css"}},
+		{"/static/*filepath", "/static/", map[string]string{"filepath": "/"}},
+		{"/static/*filepath", "/static/dir/", map[string]string{"filepath": "/dir/"}},
+		{"/static/*filepath", "/static", nil},
+		{"/static/*filepath", "/other/app.css", nil},
+		{"/*path", "/", map[string]string{"path": "/"}},
+		{"/*path/more", "/a/more", nil},
+
+		// Regex templates match the whole path and return named groups
+		{"regex:^/api/v[0-9]+/users$", "/api/v2/users", map[string]string{}},
+		{"regex:^/api/v[0-9]+/users$", "/api/vx/users", nil},
+		{"regex:^/orders/(?P<id>[0-9]+)$", "/orders/17", map[string]string{"id": "17"}},
+		{"regex:^/orders/(?P<id>[0-9]+)$", "/orders/abc", nil},
+		{"regex:([", "/anything", nil},
+	})
+}
+
+func TestGlobPathMatcher(t *testing.T) {
+	runMatcherCases(t, NewGlobPathMatcher(), []matcherCase{
+		{"/api/users", "/api/users", map[string]string{}},
+		{"/api/users/*", "/api/users/42", map[string]string{}},
+		{"/api/users/*", "/api/users/42/posts", nil},
+		{"/api/users/:id", "/api/users/42", map[string]string{"id": "42"}},
+		{"/reports/report-?.csv", "/reports/report-1.csv", map[string]string{}},
+		{"/reports/report-?.csv", "/reports/report-10.csv", nil},
+		{"/reports/[a-c]", "/reports/b", map[string]string{}},
+		{"/reports/[a-c]", "/reports/d", nil},
+
+		// "**" expands to zero or more segments
+		{"/api/**", "/api", map[string]string{}},
+		{"/api/**", "/api/v1/reports/7", map[string]string{}},
+		{"/api/**/posts", "/api/posts", map[string]string{}},
+		{"/api/**/posts", "/api/users/1/posts", map[string]string{}},
+		{"/api/**/posts", "/api/users/1/comments", nil},
+		{"/**/users/:id", "/a/b/users/9", map[string]string{"id": "9"}},
+		{"/**", "/", map[string]string{}},
+
+		// A trailing "*name" is a gin catch-all parameter
+		{"/static/*filepath", "/static/css/app.css", map[string]string{"filepath": "/css/app.css"}},
+		{"/static/*filepath", "/static/app.css", map[string]string{"filepath": "/app.css"}},
+		{"/static/*filepath", "/static/", map[string]string{"filepath": "/"}},
+		{"/static/*filepath", "/static", map[string]string{"filepath": "/"}},
+		{"/static/*filepath", "/static/*filepath", map[string]string{"filepath": "/*filepath"}},
+		{"/static/*filepath", "/other/app.css", nil},
+		{"/files/:dir/*name", "/files/a/b/c", map[string]string{"dir": "a", "name": "/b/c"}},
+		{"/**/assets/*file_path", "/v1/assets/img/x.png", map[string]string{"file_path": "/img/x.png"}},
+
+		// "*" followed by glob characters stays a single-segment glob
+		{"/static/*.css", "/static/app.css", map[string]string{}},
+		{"/static/*.css", "/static/css/app.css", nil},
+		{"/static/*.css", "/static/app.js", nil},
+		{"/static/*/app.css", "/static/css/app.css", map[string]string{}},
+
+		// Regex patterns see whatever path they are given; the manager passes
+		// route templates, so ":id" is literal there
+		{"regex:^/api/.*$", "/api/x", map[string]string{}},
+		{"regex:^/api/.*$", "/other", nil},
+		{"regex:^/orders/(?P<id>[0-9]+)$", "/orders/17", map[string]string{"id": "17"}},
+		{"regex:^/orders/(?P<id>[0-9]+)$", "/orders/:id", nil},
+		{"regex:^/orders/[^/]+$", "/orders/:id", map[string]string{}},
+	})
+}
+
+// ginRoute serves path with a gin engine that only has route registered and
+// returns the matched parameters, or nil when gin does not route the request
+func ginRoute(route, path string) map[string]string {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.RedirectTrailingSlash = false
+	engine.RedirectFixedPath = false
+
+	var params map[string]string
+	engine.GET(route, func(c *gin.Context) {
+		params = make(map[string]string)
+		for _, param := range c.Params {
+			params[param.Key] = param.Value
+		}
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	return params
+}
+
+func TestGinPathMatcherMatchesGinRouting(t *testing.T) {
+	matcher := NewGinPathMatcher()
+	cases := []struct {
+		route string
+		paths []string
+	}{
+		{"/api/users/:id", []string{"/api/users/42", "/api/users", "/api/users/42/posts"}},
+		{"/static/*filepath", []string{"/static", "/static/", "/static/app.css", "/static/css/app.css", "/static/dir/"}},
+		{"/files/:dir/*name", []string{"/files/a/b/c", "/files/a/", "/files/a"}},
+		{"/*path", []string{"/", "/a", "/a/b/"}},
+	}
+
+	for _, tt := range cases {
+		for _, path := range tt.paths {
+			t.Run(tt.route+" "+path, func(t *testing.T) {
+				want := ginRoute(tt.route, path)
+				if got := matcher.Match(tt.route, path); got != (want != nil) {
+					t.Fatalf("Match = %v, gin routed = %v", got, want != nil)
+				}
+				if want == nil {
+					return
+				}
+				if got := matcher.ExtractParams(tt.route, path); !reflect.DeepEqual(got, want) {
+					t.Fatalf("ExtractParams = %v, gin params = %v", got, want)
+				}
+			})
+		}
+	}
+}
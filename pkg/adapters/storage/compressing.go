@@ -0,0 +1,422 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec compresses stored data. The codec of a stored file is recorded in
+// the metadata record of its profile (core.ProfilingResult.ContentEncoding)
+// and by appending its extension to the file name, so that the stored file
+// can be found and decompressed without reading the record.
+type Codec interface {
+	// Name identifies the codec in file metadata, e.g. "gzip"
+	Name() string
+	// Extension is appended to the names of compressed files, e.g. ".gz"
+	Extension() string
+	// Compress returns the compressed data
+	Compress(data []byte) ([]byte, error)
+	// NewReader returns a reader that decompresses r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// gzipCodec compresses with gzip
+type gzipCodec struct {
+	level int
+}
+
+// GzipCodec returns a gzip codec with the given compression level
+// (gzip.DefaultCompression when 0)
+func GzipCodec(level int) Codec {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzipCodec{level: level}
+}
+
+// Name returns "gzip"
+func (c gzipCodec) Name() string { return "gzip" }
+
+// Extension returns ".gz"
+func (c gzipCodec) Extension() string { return ".gz" }
+
+// Compress gzips data
+func (c gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewReader returns a gzip reader
+func (c gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// zstdCodec compresses with zstd
+type zstdCodec struct {
+	level zstd.EncoderLevel
+
+	// The encoder is created on first use; EncodeAll is safe for concurrent use
+	once    sync.Once
+	encoder *zstd.Encoder
+	err     error
+}
+
+// ZstdCodec returns a zstd codec with the given encoder level
+// (zstd.SpeedDefault when 0)
+func ZstdCodec(level zstd.EncoderLevel) Codec {
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
+	return &zstdCodec{level: level}
+}
+
+// Name returns "zstd"
+func (c *zstdCodec) Name() string { return "zstd" }
+
+// Extension returns ".zst"
+func (c *zstdCodec) Extension() string { return ".zst" }
+
+// Compress compresses data with zstd
+func (c *zstdCodec) Compress(data []byte) ([]byte, error) {
+	c.once.Do(func() {
+		c.encoder, c.err = zstd.NewWriter(nil, zstd.WithEncoderLevel(c.level))
+	})
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+// NewReader returns a zstd reader
+func (c *zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+// CompressingOptions contains options for CompressingStorage
+type CompressingOptions struct {
+	// Codec compresses new files, defaults to GzipCodec
+	Codec Codec
+	// ContentTypes lists the content types to compress. Defaults to every
+	// type except core.ContentTypePprof, which is gzipped already.
+	ContentTypes []string
+	// MinSize skips files smaller than this many bytes
+	MinSize int
+}
+
+// CompressingStorage is a Storage decorator that compresses files by
+// content type and decompresses them transparently when they are read.
+// Compressed files are stored under their name plus the codec extension
+// (e.g. "trace/x.trace.zst"); callers keep using the original name.
+type CompressingStorage struct {
+	inner        core.Storage
+	codec        Codec
+	codecs       []Codec
+	contentTypes map[string]bool
+	minSize      int
+	logger       core.Logger
+}
+
+// NewCompressingStorage wraps inner with transparent compression
+func NewCompressingStorage(inner core.Storage, opts CompressingOptions, logger core.Logger) core.Storage {
+	codec := opts.Codec
+	if codec == nil {
+		codec = GzipCodec(0)
+	}
+
+	// Files written with another codec stay readable after the codec changes
+	codecs := []Codec{codec}
+	for _, known := range []Codec{GzipCodec(0), ZstdCodec(0)} {
+		if known.Extension() != codec.Extension() {
+			codecs = append(codecs, known)
+		}
+	}
+
+	var contentTypes map[string]bool
+	if len(opts.ContentTypes) > 0 {
+		contentTypes = make(map[string]bool, len(opts.ContentTypes))
+		for _, contentType := range opts.ContentTypes {
+			contentTypes[contentType] = true
+		}
+	}
+
+	return &CompressingStorage{
+		inner:        inner,
+		codec:        codec,
+		codecs:       codecs,
+		contentTypes: contentTypes,
+		minSize:      opts.MinSize,
+		logger:       logger,
+	}
+}
+
+// Save compresses data when its content type is selected and saves it
+func (c *CompressingStorage) Save(ctx context.Context, filename string, data []byte) error {
+	_, _, err := c.SaveEncoded(ctx, filename, data)
+	return err
+}
+
+// SaveEncoded saves a file like Save and returns the name of the codec it
+// was compressed with ("" when it is stored uncompressed) and the number of
+// bytes stored. The manager records both in the metadata record of the
+// profile.
+func (c *CompressingStorage) SaveEncoded(ctx context.Context, filename string, data []byte) (string, int64, error) {
+	if !c.shouldCompress(filename, data) {
+		return "", int64(len(data)), c.inner.Save(ctx, filename, data)
+	}
+
+	compressed, err := c.codec.Compress(data)
+	if err != nil {
+		c.logger.Error("Failed to compress profile", map[string]interface{}{
+			"filename": filename,
+			"codec":    c.codec.Name(),
+			"error":    err.Error(),
+		})
+		return "", 0, err
+	}
+
+	c.logger.Debug("Profile compressed", map[string]interface{}{
+		"filename":        filename,
+		"codec":           c.codec.Name(),
+		"size":            len(data),
+		"compressed_size": len(compressed),
+	})
+	if err := c.inner.Save(ctx, filename+c.codec.Extension(), compressed); err != nil {
+		return "", 0, err
+	}
+	return c.codec.Name(), int64(len(compressed)), nil
+}
+
+// List lists files matching the given pattern, reporting compressed files
+// under their original name
+func (c *CompressingStorage) List(ctx context.Context, pattern string) ([]string, error) {
+	patterns := []string{pattern}
+	for _, codec := range c.codecs {
+		patterns = append(patterns, pattern+codec.Extension())
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		stored, err := c.inner.List(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range stored {
+			name := c.originalName(file)
+			if matched, _ := filepath.Match(pattern, name); matched && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+	return files, nil
+}
+
+// originalName strips the codec extension from a stored file name
+func (c *CompressingStorage) originalName(stored string) string {
+	for _, codec := range c.codecs {
+		if strings.HasSuffix(stored, codec.Extension()) {
+			return strings.TrimSuffix(stored, codec.Extension())
+		}
+	}
+	return stored
+}
+
+// Delete deletes a file whether or not it is compressed. When the inner
+// storage cannot be read to find the stored name, the plain name and the
+// name with every codec extension are deleted.
+func (c *CompressingStorage) Delete(ctx context.Context, filename string) error {
+	if _, ok := c.inner.(core.StorageReader); !ok {
+		var firstErr error
+		for _, stored := range c.storedNames(filename) {
+			if err := c.inner.Delete(ctx, stored); err != nil && !errors.Is(err, fs.ErrNotExist) && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	stored, _, _, err := c.resolve(ctx, filename)
+	if err != nil {
+		return err
+	}
+	return c.inner.Delete(ctx, stored)
+}
+
+// storedNames returns every name a file may be stored under
+func (c *CompressingStorage) storedNames(filename string) []string {
+	names := []string{filename}
+	for _, codec := range c.codecs {
+		names = append(names, filename+codec.Extension())
+	}
+	return names
+}
+
+// Clean removes files older than maxAge. The inner storage only looks at
+// the profile part of the file names, so compressed files are cleaned
+// whatever their codec extension is.
+func (c *CompressingStorage) Clean(ctx context.Context, maxAge time.Duration) error {
+	return c.inner.Clean(ctx, maxAge)
+}
+
+// Open opens a file and decompresses it transparently
+func (c *CompressingStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	if _, ok := c.inner.(core.StorageReader); !ok {
+		return nil, core.ErrStorageNotReadable
+	}
+
+	stored, codec, _, err := c.resolve(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	return c.openStored(ctx, stored, codec)
+}
+
+// openStored opens a resolved stored file and decompresses it with codec
+// (as it is when codec is nil)
+func (c *CompressingStorage) openStored(ctx context.Context, stored string, codec Codec) (io.ReadCloser, error) {
+	rc, err := c.inner.(core.StorageReader).Open(ctx, stored)
+	if err != nil || codec == nil {
+		return rc, err
+	}
+
+	decompressed, err := codec.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &decompressingReader{ReadCloser: decompressed, underlying: rc}, nil
+}
+
+// Stat returns the decompressed size of a file and its compressed size as
+// StoredSize. The codec of a compressed file is reported as
+// core.MetadataContentEncoding.
+func (c *CompressingStorage) Stat(ctx context.Context, filename string) (core.FileInfo, error) {
+	if _, ok := c.inner.(core.StorageReader); !ok {
+		return core.FileInfo{}, core.ErrStorageNotReadable
+	}
+
+	stored, codec, info, err := c.resolve(ctx, filename)
+	if err != nil || codec == nil {
+		return info, err
+	}
+
+	size, err := c.originalSize(ctx, filename, stored, codec)
+	if err != nil {
+		return core.FileInfo{}, err
+	}
+
+	metadata := core.FileMetadata(filename)
+	for key, value := range info.Metadata {
+		if _, exists := metadata[key]; !exists {
+			metadata[key] = value
+		}
+	}
+	metadata[core.MetadataContentEncoding] = codec.Name()
+
+	return core.FileInfo{
+		Name:       filename,
+		Size:       size,
+		StoredSize: info.Size,
+		ModTime:    info.ModTime,
+		Metadata:   metadata,
+	}, nil
+}
+
+// originalSize returns the uncompressed size of a compressed profile from
+// the FileSize of its metadata record. Files without a record, such as the
+// records themselves, are small or rare and are decompressed to count
+// their bytes.
+func (c *CompressingStorage) originalSize(ctx context.Context, filename, stored string, codec Codec) (int64, error) {
+	if !strings.HasSuffix(filename, core.MetadataExtension) {
+		if record, err := c.Open(ctx, core.MetadataFilename(filename)); err == nil {
+			var result core.ProfilingResult
+			err := json.NewDecoder(record).Decode(&result)
+			record.Close()
+			if err == nil && result.Filename == filename && result.FileSize > 0 {
+				return result.FileSize, nil
+			}
+		}
+	}
+
+	rc, err := c.openStored(ctx, stored, codec)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(io.Discard, rc)
+}
+
+// resolve finds the stored name of a file, the codec it was compressed
+// with (nil when it is stored uncompressed) and the file info of the
+// stored file
+func (c *CompressingStorage) resolve(ctx context.Context, filename string) (string, Codec, core.FileInfo, error) {
+	reader := c.inner.(core.StorageReader)
+	for _, codec := range c.codecs {
+		stored := filename + codec.Extension()
+		if info, err := reader.Stat(ctx, stored); err == nil {
+			return stored, codec, info, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", nil, core.FileInfo{}, err
+		}
+	}
+	info, err := reader.Stat(ctx, filename)
+	if err != nil {
+		return "", nil, core.FileInfo{}, err
+	}
+	return filename, nil, info, nil
+}
+
+// shouldCompress checks the size and content type of a file
+func (c *CompressingStorage) shouldCompress(filename string, data []byte) bool {
+	if len(data) < c.minSize {
+		return false
+	}
+
+	contentType := core.FileMetadata(filename)[core.MetadataContentType]
+	if c.contentTypes != nil {
+		return c.contentTypes[contentType]
+	}
+	return contentType != core.ContentTypePprof
+}
+
+// decompressingReader closes both the decompressor and the stored file
+type decompressingReader struct {
+	io.ReadCloser
+	underlying io.Closer
+}
+
+// Close closes the decompressor and the stored file
+func (r *decompressingReader) Close() error {
+	err := r.ReadCloser.Close()
+	if closeErr := r.underlying.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/adapters/logger"
+	"github.com/aclstack/gin-pprof/pkg/core"
+)
+
+// reverseCodec is a custom codec with an extension the built-in codecs do
+// not know; it "compresses" by reversing the data
+type reverseCodec struct{}
+
+func (reverseCodec) Name() string      { return "reverse" }
+func (reverseCodec) Extension() string { return ".rev" }
+
+func (reverseCodec) Compress(data []byte) ([]byte, error) {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed, nil
+}
+
+func (c reverseCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reversed, _ := c.Compress(data)
+	return io.NopCloser(bytes.NewReader(reversed)), nil
+}
+
+func newCompressingFileStorage(t *testing.T, codec Codec) (string, core.Storage) {
+	t.Helper()
+	dir := t.TempDir()
+	inner, err := NewFileStorage(dir, logger.NewNoopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, NewCompressingStorage(inner, CompressingOptions{Codec: codec}, logger.NewNoopLogger())
+}
+
+func TestCompressingStorageRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{GzipCodec(0), ZstdCodec(0), reverseCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			dir, s := newCompressingFileStorage(t, codec)
+			ctx := context.Background()
+			data := bytes.Repeat([]byte("trace event "), 100)
+
+			encoding, storedSize, err := s.(core.EncodingStorage).SaveEncoded(ctx, "trace/profile_a.trace", data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoding != codec.Name() {
+				t.Fatalf("encoding = %q, want %q", encoding, codec.Name())
+			}
+			stored, err := os.Stat(filepath.Join(dir, "trace", "profile_a.trace"+codec.Extension()))
+			if err != nil {
+				t.Fatalf("compressed file not stored: %v", err)
+			}
+			if storedSize != stored.Size() {
+				t.Fatalf("stored size = %d, file has %d bytes", storedSize, stored.Size())
+			}
+
+			// pprof files are gzipped already and stored as they are
+			encoding, storedSize, err = s.(core.EncodingStorage).SaveEncoded(ctx, "cpu/profile_b.pprof", []byte("pprof"))
+			if err != nil || encoding != "" || storedSize != 5 {
+				t.Fatalf("pprof encoding = %q, stored size = %d, %v", encoding, storedSize, err)
+			}
+
+			files, err := s.List(ctx, "*/*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(files)
+			if strings.Join(files, ",") != "cpu/profile_b.pprof,trace/profile_a.trace" {
+				t.Fatalf("List = %v", files)
+			}
+
+			got := readAll(t, s.(core.StorageReader), "trace/profile_a.trace")
+			if !bytes.Equal(got, data) {
+				t.Fatalf("Open returned %q", got)
+			}
+			info, err := s.(core.StorageReader).Stat(ctx, "trace/profile_a.trace")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size != int64(len(data)) || info.StoredSize != stored.Size() || info.Metadata[core.MetadataContentEncoding] != codec.Name() {
+				t.Fatalf("Stat = %+v", info)
+			}
+
+			if err := s.Delete(ctx, "trace/profile_a.trace"); err != nil {
+				t.Fatal(err)
+			}
+			if files, _ := s.List(ctx, "trace/*"); len(files) != 0 {
+				t.Fatalf("files left after Delete: %v", files)
+			}
+		})
+	}
+}
+
+func TestCompressingStorageCleanAnyCodec(t *testing.T) {
+	dir, s := newCompressingFileStorage(t, reverseCodec{})
+	ctx := context.Background()
+
+	for _, name := range []string{"trace/profile_old.trace", "cpu/profile_old.pprof", "cpu/profile_new.pprof"} {
+		if err := s.Save(ctx, name, []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Save(ctx, core.MetadataFilename(name), []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cpu", "notes.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{
+		"trace/profile_old.trace.rev",
+		"trace/profile_old.trace.json.rev",
+		"cpu/profile_old.pprof",
+		"cpu/profile_old.pprof.json.rev",
+		"cpu/notes.txt",
+	} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Clean(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var left []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(left)
+	want := "cpu/notes.txt,cpu/profile_new.pprof,cpu/profile_new.pprof.json.rev"
+	if strings.Join(left, ",") != want {
+		t.Fatalf("files after Clean = %v, want %s", left, want)
+	}
+}
+
+// openCountingStorage counts the files opened from the inner storage
+type openCountingStorage struct {
+	core.Storage
+	opened []string
+}
+
+func (s *openCountingStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	s.opened = append(s.opened, filename)
+	return s.Storage.(core.StorageReader).Open(ctx, filename)
+}
+
+func (s *openCountingStorage) Stat(ctx context.Context, filename string) (core.FileInfo, error) {
+	return s.Storage.(core.StorageReader).Stat(ctx, filename)
+}
+
+func TestCompressingStorageStatReadsSizeFromRecord(t *testing.T) {
+	inner := &openCountingStorage{Storage: NewMemoryStorage(logger.NewNoopLogger())}
+	s := NewCompressingStorage(inner, CompressingOptions{Codec: GzipCodec(0)}, logger.NewNoopLogger())
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("trace event "), 100)
+
+	// Without a record the profile is decompressed to count its bytes
+	if err := s.Save(ctx, "trace/profile_a.trace", data); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.(core.StorageReader).Stat(ctx, "trace/profile_a.trace")
+	if err != nil || info.Size != int64(len(data)) {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+
+	// With a record the profile is not opened
+	record := fmt.Sprintf(`{"filename":"trace/profile_a.trace","file_size":%d}`, len(data))
+	if err := s.Save(ctx, core.MetadataFilename("trace/profile_a.trace"), []byte(record)); err != nil {
+		t.Fatal(err)
+	}
+	inner.opened = nil
+	info, err = s.(core.StorageReader).Stat(ctx, "trace/profile_a.trace")
+	if err != nil || info.Size != int64(len(data)) || info.StoredSize >= info.Size {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+	for _, name := range inner.opened {
+		if name == "trace/profile_a.trace.gz" {
+			t.Fatalf("Stat decompressed the profile: opened %v", inner.opened)
+		}
+	}
+}
+
+// writeOnlyStorage hides the StorageReader methods of its inner storage
+type writeOnlyStorage struct {
+	inner core.Storage
+}
+
+func (s writeOnlyStorage) Save(ctx context.Context, filename string, data []byte) error {
+	return s.inner.Save(ctx, filename, data)
+}
+
+func (s writeOnlyStorage) List(ctx context.Context, pattern string) ([]string, error) {
+	return s.inner.List(ctx, pattern)
+}
+
+func (s writeOnlyStorage) Delete(ctx context.Context, filename string) error {
+	return s.inner.Delete(ctx, filename)
+}
+
+func (s writeOnlyStorage) Clean(ctx context.Context, maxAge time.Duration) error {
+	return s.inner.Clean(ctx, maxAge)
+}
+
+func TestCompressingStorageDeleteWithoutReader(t *testing.T) {
+	dir := t.TempDir()
+	files, err := NewFileStorage(dir, logger.NewNoopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Files written with gzip and then with zstd
+	gzipped := NewCompressingStorage(writeOnlyStorage{files}, CompressingOptions{Codec: GzipCodec(0)}, logger.NewNoopLogger())
+	if err := gzipped.Save(ctx, "trace/profile_a.trace", []byte("gzip")); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCompressingStorage(writeOnlyStorage{files}, CompressingOptions{Codec: ZstdCodec(0)}, logger.NewNoopLogger())
+	if err := s.Save(ctx, "trace/profile_b.trace", []byte("zstd")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"trace/profile_a.trace", "trace/profile_b.trace"} {
+		if err := s.Delete(ctx, name); err != nil {
+			t.Fatalf("Delete(%s): %v", name, err)
+		}
+	}
+	if left, _ := files.List(ctx, "trace/*"); len(left) != 0 {
+		t.Fatalf("files left after Delete: %v", left)
+	}
+}
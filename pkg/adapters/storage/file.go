@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -112,9 +114,50 @@ func (f *FileStorage) Delete(ctx context.Context, filename string) error {
 	return nil
 }
 
+// Open opens a stored profile for reading
+func (f *FileStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	filePath, err := f.resolve("open", filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+// Stat returns the size, modification time and metadata of a stored profile
+func (f *FileStorage) Stat(ctx context.Context, filename string) (core.FileInfo, error) {
+	filePath, err := f.resolve("stat", filename)
+	if err != nil {
+		return core.FileInfo{}, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return core.FileInfo{}, err
+	}
+	if stat.IsDir() {
+		return core.FileInfo{}, &fs.PathError{Op: "stat", Path: filename, Err: fs.ErrNotExist}
+	}
+
+	return core.FileInfo{
+		Name:     filename,
+		Size:     stat.Size(),
+		ModTime:  stat.ModTime(),
+		Metadata: core.FileMetadata(filepath.ToSlash(filename)),
+	}, nil
+}
+
+// resolve returns the path of filename below the base directory, rejecting
+// names that would escape it
+func (f *FileStorage) resolve(op, filename string) (string, error) {
+	if !filepath.IsLocal(filename) {
+		return "", &fs.PathError{Op: op, Path: filename, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(f.baseDir, filename), nil
+}
+
 // Clean removes files older than maxAge
 func (f *FileStorage) Clean(ctx context.Context, maxAge time.Duration) error {
-	files, err := f.List(ctx, "*.pprof")
+	files, err := f.listProfiles()
 	if err != nil {
 		return err
 	}
@@ -147,6 +190,45 @@ func (f *FileStorage) Clean(ctx context.Context, maxAge time.Duration) error {
 	return nil
 }
 
+// isProfileFile reports whether a stored file belongs to a profile: a
+// "<type>/profile_*" file with a ".pprof" or ".trace" artifact extension,
+// optionally followed by further extensions such as ".json" for metadata
+// records or a compression codec extension. Storages only clean these files,
+// so unrelated files next to the profiles are never deleted.
+func isProfileFile(name string) bool {
+	dir, base, found := strings.Cut(name, "/")
+	if !found || dir == "" || strings.Contains(base, "/") || !strings.HasPrefix(base, "profile_") {
+		return false
+	}
+	return strings.Contains(base, ".pprof") || strings.Contains(base, ".trace")
+}
+
+// listProfiles returns all profile files below the base directory, including
+// their metadata records and compressed variants, whatever extensions
+// decorators such as CompressingStorage append
+func (f *FileStorage) listProfiles() ([]string, error) {
+	var result []string
+	err := filepath.WalkDir(f.baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.baseDir, path)
+		if err != nil {
+			return nil
+		}
+		if isProfileFile(filepath.ToSlash(rel)) {
+			result = append(result, rel)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
 // sanitizePath cleans path for use in filenames
 func sanitizePath(path string) string {
 	sanitized := strings.ReplaceAll(path, "/", "_")
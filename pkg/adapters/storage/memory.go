@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/fs"
 	"path/filepath"
 	"sync"
 	"time"
@@ -85,6 +88,38 @@ func (m *MemoryStorage) Delete(ctx context.Context, filename string) error {
 	return nil
 }
 
+// Open returns a reader over a stored profile
+func (m *MemoryStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, exists := m.files[filename]
+	if !exists {
+		return nil, &fs.PathError{Op: "open", Path: filename, Err: fs.ErrNotExist}
+	}
+
+	// Saved data is never modified in place, so it can be read without copying
+	return io.NopCloser(bytes.NewReader(file.data)), nil
+}
+
+// Stat returns the size, modification time and metadata of a stored profile
+func (m *MemoryStorage) Stat(ctx context.Context, filename string) (core.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, exists := m.files[filename]
+	if !exists {
+		return core.FileInfo{}, &fs.PathError{Op: "stat", Path: filename, Err: fs.ErrNotExist}
+	}
+
+	return core.FileInfo{
+		Name:     filename,
+		Size:     int64(len(file.data)),
+		ModTime:  file.modTime,
+		Metadata: core.FileMetadata(filename),
+	}, nil
+}
+
 // Clean removes files older than maxAge
 func (m *MemoryStorage) Clean(ctx context.Context, maxAge time.Duration) error {
 	m.mu.Lock()
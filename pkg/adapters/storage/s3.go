@@ -0,0 +1,501 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aclstack/gin-pprof/pkg/core"
+)
+
+const (
+	// defaultS3Region is used when S3Options.Region is empty
+	defaultS3Region = "us-east-1"
+	// defaultS3PartSize is the default multipart part size and threshold
+	defaultS3PartSize = 16 << 20
+	// minS3PartSize is the smallest part size S3 accepts for all but the last part
+	minS3PartSize = 5 << 20
+)
+
+// S3Options contains options for S3-compatible object storage
+type S3Options struct {
+	// Bucket is the bucket profiles are stored in (required)
+	Bucket string
+	// Prefix is prepended to every object key, e.g. "profiles/my-service".
+	// Clean only deletes profile objects ("<type>/profile_*") below it, but
+	// without a prefix it scans the whole bucket, so use a dedicated prefix
+	// or bucket when the bucket is shared.
+	Prefix string
+	// Region is the bucket region, defaults to "us-east-1"
+	Region string
+	// Endpoint is the service URL, e.g. "http://localhost:9000" for MinIO.
+	// Defaults to "https://s3.<region>.amazonaws.com".
+	Endpoint string
+	// UsePathStyle addresses the bucket as <endpoint>/<bucket> instead of
+	// <bucket>.<endpoint>; MinIO and most self-hosted services need it
+	UsePathStyle bool
+	// AccessKeyID, SecretAccessKey and SessionToken are static credentials.
+	// When empty they are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+	// and AWS_SESSION_TOKEN; without credentials requests are not signed.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// PartSize is the multipart part size; larger profiles are uploaded in
+	// parts. Defaults to 16MiB, the minimum is 5MiB.
+	PartSize int64
+	// HTTPClient is used for requests, defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// S3Storage implements Storage interface using S3-compatible object storage
+type S3Storage struct {
+	bucket       string
+	prefix       string
+	region       string
+	endpoint     *url.URL
+	usePathStyle bool
+	creds        s3Credentials
+	partSize     int64
+	client       *http.Client
+	logger       core.Logger
+}
+
+// S3Error is an error response returned by the object storage
+type S3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+// Error returns the error message
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// Is reports missing objects as fs.ErrNotExist
+func (e *S3Error) Is(target error) bool {
+	return target == fs.ErrNotExist && e.StatusCode == http.StatusNotFound
+}
+
+// s3Object is an entry of a ListObjectsV2 response
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+// NewS3Storage creates a new S3Storage
+func NewS3Storage(opts S3Options, logger core.Logger) (core.Storage, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme == "" || endpointURL.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", endpoint)
+	}
+
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = defaultS3PartSize
+	}
+	if partSize < minS3PartSize {
+		return nil, fmt.Errorf("s3: part size must be at least %d bytes", minS3PartSize)
+	}
+
+	creds := s3Credentials{
+		accessKeyID:     opts.AccessKeyID,
+		secretAccessKey: opts.SecretAccessKey,
+		sessionToken:    opts.SessionToken,
+	}
+	if creds.accessKeyID == "" && creds.secretAccessKey == "" {
+		creds = s3Credentials{
+			accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if strings.Trim(opts.Prefix, "/") == "" {
+		logger.Warn("S3 storage has no key prefix, cleanup scans the whole bucket", map[string]interface{}{
+			"bucket": opts.Bucket,
+			"hint":   "Set S3Options.Prefix when the bucket holds other data",
+		})
+	}
+
+	return &S3Storage{
+		bucket:       opts.Bucket,
+		prefix:       strings.Trim(opts.Prefix, "/"),
+		region:       region,
+		endpoint:     endpointURL,
+		usePathStyle: opts.UsePathStyle,
+		creds:        creds,
+		partSize:     partSize,
+		client:       client,
+		logger:       logger,
+	}, nil
+}
+
+// Save uploads profile data, using a multipart upload above the part size
+func (s *S3Storage) Save(ctx context.Context, filename string, data []byte) error {
+	key := s.key(filename)
+	contentType := core.FileMetadata(filename)[core.MetadataContentType]
+
+	var err error
+	if int64(len(data)) > s.partSize {
+		err = s.multipartUpload(ctx, key, contentType, data)
+	} else {
+		header := http.Header{}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		var resp *http.Response
+		resp, err = s.do(ctx, http.MethodPut, key, nil, header, data)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		s.logger.Error("Failed to upload profile", map[string]interface{}{
+			"filename": filename,
+			"bucket":   s.bucket,
+			"error":    err.Error(),
+		})
+		return err
+	}
+
+	s.logger.Info("Profile saved", map[string]interface{}{
+		"filename": filename,
+		"size":     len(data),
+		"bucket":   s.bucket,
+		"key":      key,
+	})
+
+	return nil
+}
+
+// List lists files matching the given pattern
+func (s *S3Storage) List(ctx context.Context, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	// Only list below the literal part of the pattern
+	literal := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		literal = pattern[:i]
+	}
+
+	objects, err := s.listObjects(ctx, literal)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, object := range objects {
+		name := s.filename(object.Key)
+		if matched, _ := path.Match(pattern, name); matched {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// Delete deletes a file from storage
+func (s *S3Storage) Delete(ctx context.Context, filename string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.key(filename), nil, nil, nil)
+	if err != nil {
+		s.logger.Error("Failed to delete profile object", map[string]interface{}{
+			"filename": filename,
+			"bucket":   s.bucket,
+			"error":    err.Error(),
+		})
+		return err
+	}
+	resp.Body.Close()
+
+	s.logger.Info("Profile deleted", map[string]interface{}{
+		"filename": filename,
+	})
+
+	return nil
+}
+
+// Clean removes profile objects whose LastModified is older than maxAge.
+// Other objects below the prefix are left alone.
+func (s *S3Storage) Clean(ctx context.Context, maxAge time.Duration) error {
+	objects, err := s.listObjects(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	cleanedCount := 0
+	for _, object := range objects {
+		if !isProfileFile(s.filename(object.Key)) {
+			continue
+		}
+		if now.Sub(object.LastModified) > maxAge {
+			if err := s.Delete(ctx, s.filename(object.Key)); err == nil {
+				cleanedCount++
+			}
+		}
+	}
+
+	if cleanedCount > 0 {
+		s.logger.Info("Profile cleanup completed", map[string]interface{}{
+			"cleaned_files": cleanedCount,
+			"max_age":       maxAge.String(),
+			"bucket":        s.bucket,
+		})
+	}
+
+	return nil
+}
+
+// Open downloads a stored profile
+func (s *S3Storage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.key(filename), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Stat returns the size, modification time and metadata of a stored profile
+func (s *S3Storage) Stat(ctx context.Context, filename string) (core.FileInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.key(filename), nil, nil, nil)
+	if err != nil {
+		return core.FileInfo{}, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	metadata := core.FileMetadata(filename)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		metadata[core.MetadataContentType] = contentType
+	}
+
+	return core.FileInfo{
+		Name:     filename,
+		Size:     resp.ContentLength,
+		ModTime:  modTime,
+		Metadata: metadata,
+	}, nil
+}
+
+// multipartUpload uploads data in parts and aborts the upload on failure
+func (s *S3Storage) multipartUpload(ctx context.Context, key, contentType string, data []byte) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("s3: decode multipart upload: %w", err)
+	}
+
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+	for offset, number := int64(0), 1; offset < int64(len(data)); offset, number = offset+s.partSize, number+1 {
+		end := offset + s.partSize
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadID}}
+		resp, err := s.do(ctx, http.MethodPut, key, query, nil, data[offset:end])
+		if err != nil {
+			s.abortUpload(ctx, key, initiated.UploadID)
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		s.abortUpload(ctx, key, initiated.UploadID)
+		return err
+	}
+
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {initiated.UploadID}}, nil, body)
+	if err != nil {
+		s.abortUpload(ctx, key, initiated.UploadID)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// CompleteMultipartUpload can fail after a 200 status with an error body
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.abortUpload(ctx, key, initiated.UploadID)
+		return err
+	}
+	if bytes.Contains(respBody, []byte("<Error>")) {
+		s3Err := &S3Error{StatusCode: resp.StatusCode}
+		xml.Unmarshal(respBody, s3Err)
+		s.abortUpload(ctx, key, initiated.UploadID)
+		return s3Err
+	}
+	return nil
+}
+
+// abortUpload aborts a multipart upload so its parts do not linger in the bucket
+func (s *S3Storage) abortUpload(ctx context.Context, key, uploadID string) {
+	resp, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		s.logger.Warn("Failed to abort multipart upload", map[string]interface{}{
+			"key":       key,
+			"upload_id": uploadID,
+			"error":     err.Error(),
+		})
+		return
+	}
+	resp.Body.Close()
+}
+
+// listObjects lists every object whose file name starts with namePrefix
+func (s *S3Storage) listObjects(ctx context.Context, namePrefix string) ([]s3Object, error) {
+	keyPrefix := s.key(namePrefix)
+
+	var objects []s3Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if keyPrefix != "" {
+			query.Set("prefix", keyPrefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents              []s3Object `xml:"Contents"`
+			IsTruncated           bool       `xml:"IsTruncated"`
+			NextContinuationToken string     `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: decode object list: %w", err)
+		}
+
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// do sends a signed request for key (the bucket itself when key is empty)
+// and turns error responses into *S3Error
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	escapedPath := strings.TrimSuffix(u.EscapedPath(), "/")
+	if s.usePathStyle {
+		escapedPath += "/" + s3Escape(s.bucket, true)
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	if key != "" || !s.usePathStyle {
+		escapedPath += "/" + s3Escape(key, false)
+	}
+
+	unescaped, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return nil, err
+	}
+	u.Path, u.RawPath = unescaped, escapedPath
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if s.creds.accessKeyID != "" {
+		signV4(req, s.creds, s.region, sha256Hex(body), time.Now())
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		s3Err := &S3Error{StatusCode: resp.StatusCode}
+		if data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil {
+			xml.Unmarshal(data, s3Err)
+		}
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+// key returns the object key of a file name
+func (s *S3Storage) key(filename string) string {
+	filename = strings.TrimPrefix(filename, "/")
+	if s.prefix == "" {
+		return filename
+	}
+	return s.prefix + "/" + filename
+}
+
+// filename returns the file name of an object key
+func (s *S3Storage) filename(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, s.prefix+"/")
+}
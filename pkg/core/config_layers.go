@@ -0,0 +1,107 @@
+package core
+
+import "time"
+
+// 任务来源标记，按需触发的任务为SourceTrigger
+const (
+	// SourceFile 标记来自本地配置文件的任务
+	SourceFile = "file"
+	// SourceNacos 标记来自Nacos的任务
+	SourceNacos = "nacos"
+	// SourceRuntime 标记通过管理接口在运行时创建的任务
+	SourceRuntime = "runtime"
+)
+
+// configLayer 表示一个配置来源及其最近一次加载的任务
+type configLayer struct {
+	provider  ConfigProvider
+	source    string
+	tasks     []ProfilingTask
+	firstSeen *FirstSeenTracker
+}
+
+// newConfigLayer 创建配置层
+func newConfigLayer(provider ConfigProvider, source string) *configLayer {
+	return &configLayer{provider: provider, source: source, firstSeen: NewFirstSeenTracker()}
+}
+
+// AddConfigProvider 添加一个配置层，其任务与已有配置合并。后添加的配置层优先，
+// 任务ID相同时覆盖之前配置层中的任务；未设置来源的任务会标记为source
+func (m *Manager) AddConfigProvider(provider ConfigProvider, source string) {
+	layer := newConfigLayer(provider, source)
+
+	m.layersMu.Lock()
+	m.layers = append(m.layers, layer)
+	m.layersMu.Unlock()
+
+	go m.startConfigSync(layer)
+}
+
+// updateLayer 更新配置层的任务并重新合并所有配置层。
+// 内置配置提供器已解析ttl，这里为自定义提供器传入的仅有ttl的任务计算expires_at
+func (m *Manager) updateLayer(layer *configLayer, tasks []ProfilingTask) {
+	m.layersMu.Lock()
+	defer m.layersMu.Unlock()
+
+	layer.tasks = resolveUnresolvedTTL(layer.firstSeen, tasks, time.Now())
+	m.updateTasks(mergeLayers(m.layers))
+}
+
+// resolveUnresolvedTTL 只为设置了ttl但没有expires_at的任务计算过期时间。
+// 已设置expires_at的任务由提供器解析过ttl（如运行时任务更新后重新计算的期限），
+// 保持不变，否则会被首次加载时间提前过期
+func resolveUnresolvedTTL(tracker *FirstSeenTracker, tasks []ProfilingTask, now time.Time) []ProfilingTask {
+	var pending []ProfilingTask
+	var positions []int
+	for i, task := range tasks {
+		if task.TTL > 0 && task.ExpiresAt.IsZero() {
+			pending = append(pending, task)
+			positions = append(positions, i)
+		}
+	}
+
+	// 即使没有待解析的任务也调用Resolve，使记录器遗忘已移除的任务
+	resolved := tracker.Resolve(pending, now)
+	if len(pending) == 0 {
+		return tasks
+	}
+
+	result := append([]ProfilingTask(nil), tasks...)
+	for j, i := range positions {
+		result[i] = resolved[j]
+	}
+	return result
+}
+
+// mergeLayers 按顺序合并配置层的任务，后面配置层中ID相同的任务覆盖前面的任务
+func mergeLayers(layers []*configLayer) []ProfilingTask {
+	var merged []ProfilingTask
+	positions := make(map[string]int)
+
+	for _, layer := range layers {
+		seenInLayer := make(map[string]bool)
+		for _, task := range layer.tasks {
+			if task.Source == "" {
+				task.Source = layer.source
+			}
+
+			id := task.ID
+			if id == "" {
+				id = task.GenerateID()
+			}
+
+			// 同一配置层内的重复ID由updateTasks追加序号处理
+			if pos, exists := positions[id]; exists && !seenInLayer[id] {
+				merged[pos] = task
+				seenInLayer[id] = true
+				continue
+			}
+
+			positions[id] = len(merged)
+			seenInLayer[id] = true
+			merged = append(merged, task)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// cpuBroker multiplexes the single process-wide CPU profile between
+// concurrent sessions. The underlying profile runs while at least one session
+// is attached. Whenever a session leaves, the profile is rotated: the samples
+// collected so far are handed to every attached session and a new profile is
+// started for the sessions that remain.
+type cpuBroker struct {
+	mu       sync.Mutex
+	buffer   *bytes.Buffer
+	active   bool
+	sessions map[*CPUProfileSession][][]byte
+
+	// startProfile and stopProfile control the runtime CPU profile
+	startProfile func(w io.Writer) error
+	stopProfile  func()
+}
+
+// defaultCPUBroker is shared by all CPU sessions in the process
+var defaultCPUBroker = newCPUBroker()
+
+// newCPUBroker creates a new CPU profile broker
+func newCPUBroker() *cpuBroker {
+	return &cpuBroker{
+		sessions:     make(map[*CPUProfileSession][][]byte),
+		startProfile: pprof.StartCPUProfile,
+		stopProfile:  pprof.StopCPUProfile,
+	}
+}
+
+// join attaches a session, starting the underlying profile if needed
+func (b *cpuBroker) join(session *CPUProfileSession) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.active {
+		if err := b.start(); err != nil {
+			return err
+		}
+	}
+
+	b.sessions[session] = nil
+	return nil
+}
+
+// leave detaches a session and returns the profile segments recorded while
+// it was attached
+func (b *cpuBroker) leave(session *CPUProfileSession) ([][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.sessions[session]; !exists {
+		return nil, fmt.Errorf("cpu session not attached")
+	}
+
+	if b.active {
+		b.stopProfile()
+		b.active = false
+
+		segment := b.buffer.Bytes()
+		for s, segments := range b.sessions {
+			b.sessions[s] = append(segments, segment)
+		}
+	}
+
+	segments := b.sessions[session]
+	delete(b.sessions, session)
+
+	// If the restart fails the remaining sessions keep the segments
+	// collected so far and the next join retries
+	if len(b.sessions) > 0 {
+		_ = b.start()
+	}
+
+	return segments, nil
+}
+
+// start starts the underlying CPU profile
+func (b *cpuBroker) start() error {
+	buffer := new(bytes.Buffer)
+	if err := b.startProfile(buffer); err != nil {
+		return fmt.Errorf("failed to start CPU profiling: %w", err)
+	}
+
+	b.buffer = buffer
+	b.active = true
+	return nil
+}
+
+// sessionLabels returns the pprof labels carried by ctx
+func sessionLabels(ctx context.Context) map[string]string {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	return labels
+}
+
+// buildCPUView merges the segments of a session into a single profile that
+// only contains samples carrying all of the given labels
+func buildCPUView(segments [][]byte, labels map[string]string, startTime, endTime time.Time) ([]byte, error) {
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	// Fast path: a single unfiltered segment is already a complete profile
+	if len(segments) == 1 && len(labels) == 0 {
+		return segments[0], nil
+	}
+
+	profiles := make([]*profile.Profile, 0, len(segments))
+	for _, segment := range segments {
+		p, err := profile.ParseData(segment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cpu profile segment: %w", err)
+		}
+		if len(labels) > 0 {
+			filterSamplesByLabels(p, labels)
+		}
+		profiles = append(profiles, p)
+	}
+
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge cpu profile segments: %w", err)
+	}
+	merged = merged.Compact()
+	merged.TimeNanos = startTime.UnixNano()
+	merged.DurationNanos = endTime.Sub(startTime).Nanoseconds()
+
+	buffer := new(bytes.Buffer)
+	if err := merged.Write(buffer); err != nil {
+		return nil, fmt.Errorf("failed to write cpu profile: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// filterSamplesByLabels drops samples that do not carry all of the labels
+func filterSamplesByLabels(p *profile.Profile, labels map[string]string) {
+	samples := p.Sample[:0]
+	for _, sample := range p.Sample {
+		if sampleHasLabels(sample, labels) {
+			samples = append(samples, sample)
+		}
+	}
+	p.Sample = samples
+}
+
+// sampleHasLabels checks whether a sample carries all of the labels
+func sampleHasLabels(sample *profile.Sample, labels map[string]string) bool {
+	for key, value := range labels {
+		found := false
+		for _, v := range sample.Label[key] {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// fakeCPUProfile replaces the runtime CPU profile of a broker. Every started
+// profile writes "segment<n>" so tests can tell the segments apart.
+type fakeCPUProfile struct {
+	starts int
+	stops  int
+	fail   bool
+}
+
+func newFakeBroker(fake *fakeCPUProfile) *cpuBroker {
+	b := newCPUBroker()
+	b.startProfile = func(w io.Writer) error {
+		if fake.fail {
+			return errors.New("cpu profiling already in use")
+		}
+		fake.starts++
+		_, err := fmt.Fprintf(w, "segment%d", fake.starts)
+		return err
+	}
+	b.stopProfile = func() { fake.stops++ }
+	return b
+}
+
+func segmentNames(segments [][]byte) []string {
+	names := make([]string, len(segments))
+	for i, segment := range segments {
+		names[i] = string(segment)
+	}
+	return names
+}
+
+// burnCPU keeps the goroutine busy until d has elapsed
+func burnCPU(d time.Duration) int {
+	n := 0
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		for i := 0; i < 100000; i++ {
+			n += i % 7
+		}
+	}
+	return n
+}
+
+func TestCPUBrokerOverlappingLabelledSessions(t *testing.T) {
+	ids := []string{"session-a", "session-b"}
+	burn := []time.Duration{300 * time.Millisecond, 600 * time.Millisecond}
+
+	var joined, done sync.WaitGroup
+	joined.Add(len(ids))
+	done.Add(len(ids))
+	results := make([][]byte, len(ids))
+	errs := make([]error, len(ids))
+
+	for i, id := range ids {
+		go func(i int, id string) {
+			defer done.Done()
+			labels := ProfileLabels("/work", "GET", "/work", id)
+			pprof.Do(context.Background(), labels, func(ctx context.Context) {
+				session, err := NewCPUProfileSession(ctx, ProfilingTask{})
+				joined.Done()
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				// Both sessions are attached before either one stops
+				joined.Wait()
+				burnCPU(burn[i])
+				results[i], errs[i] = session.Stop()
+			})
+		}(i, id)
+	}
+	done.Wait()
+
+	for i, id := range ids {
+		if errs[i] != nil {
+			t.Fatalf("session %s: %v", id, errs[i])
+		}
+		p, err := profile.ParseData(results[i])
+		if err != nil {
+			t.Fatalf("session %s: parse profile: %v", id, err)
+		}
+		if len(p.Sample) == 0 {
+			t.Fatalf("session %s: profile has no samples", id)
+		}
+		for _, sample := range p.Sample {
+			if got := sample.Label[LabelProfileID]; len(got) != 1 || got[0] != id {
+				t.Fatalf("session %s: sample labelled %v", id, got)
+			}
+		}
+	}
+}
+
+func TestCPUBrokerFailedRestart(t *testing.T) {
+	fake := &fakeCPUProfile{}
+	b := newFakeBroker(fake)
+	first, second := &CPUProfileSession{}, &CPUProfileSession{}
+
+	if err := b.join(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.join(second); err != nil {
+		t.Fatal(err)
+	}
+
+	// The profile cannot be restarted after the first session leaves
+	fake.fail = true
+	segments, err := b.leave(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentNames(segments); len(got) != 1 || got[0] != "segment1" {
+		t.Fatalf("first session segments = %v", got)
+	}
+	if b.active {
+		t.Fatal("broker reports an active profile after a failed restart")
+	}
+
+	// The remaining session keeps the segment collected before the failure
+	segments, err = b.leave(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentNames(segments); len(got) != 1 || got[0] != "segment1" {
+		t.Fatalf("second session segments = %v", got)
+	}
+	if fake.stops != 1 {
+		t.Fatalf("stops = %d, want 1", fake.stops)
+	}
+
+	// The next join retries
+	fake.fail = false
+	third := &CPUProfileSession{}
+	if err := b.join(third); err != nil {
+		t.Fatal(err)
+	}
+	if !b.active || fake.starts != 2 {
+		t.Fatalf("active = %v, starts = %d after rejoin", b.active, fake.starts)
+	}
+	segments, err = b.leave(third)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentNames(segments); len(got) != 1 || got[0] != "segment2" {
+		t.Fatalf("third session segments = %v", got)
+	}
+}
+
+func TestCPUBrokerJoinFailure(t *testing.T) {
+	fake := &fakeCPUProfile{fail: true}
+	b := newFakeBroker(fake)
+
+	if err := b.join(&CPUProfileSession{}); err == nil {
+		t.Fatal("join succeeded although the profile could not start")
+	}
+	if len(b.sessions) != 0 {
+		t.Fatalf("failed join left %d attached sessions", len(b.sessions))
+	}
+}
+
+func TestCPUBrokerLeaveWithoutJoin(t *testing.T) {
+	fake := &fakeCPUProfile{}
+	b := newFakeBroker(fake)
+	attached := &CPUProfileSession{}
+	if err := b.join(attached); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.leave(&CPUProfileSession{}); err == nil {
+		t.Fatal("leave succeeded for a session that never joined")
+	}
+	if !b.active || fake.stops != 0 {
+		t.Fatalf("unknown session disturbed the profile: active = %v, stops = %d", b.active, fake.stops)
+	}
+	if _, exists := b.sessions[attached]; !exists {
+		t.Fatal("attached session was detached")
+	}
+
+	if _, err := b.leave(attached); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.leave(attached); err == nil {
+		t.Fatal("second leave of the same session succeeded")
+	}
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+
+	"github.com/google/pprof/profile"
+)
+
+// snapshotProfile writes the named runtime profile in protobuf format
+func snapshotProfile(name string) ([]byte, error) {
+	p := pprof.Lookup(name)
+	if p == nil {
+		return nil, fmt.Errorf("%s profile not found", name)
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := p.WriteTo(buffer, 0); err != nil {
+		return nil, fmt.Errorf("failed to write %s profile: %w", name, err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// diffProfiles subtracts the before snapshot from the after snapshot so only
+// the samples recorded between the two remain
+func diffProfiles(before, after []byte) ([]byte, error) {
+	base, err := profile.ParseData(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base profile: %w", err)
+	}
+
+	current, err := profile.ParseData(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current profile: %w", err)
+	}
+
+	base.Scale(-1)
+	delta, err := profile.Merge([]*profile.Profile{current, base})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge profiles: %w", err)
+	}
+
+	// Drop samples that cancelled out completely
+	samples := delta.Sample[:0]
+	for _, sample := range delta.Sample {
+		for _, value := range sample.Value {
+			if value != 0 {
+				samples = append(samples, sample)
+				break
+			}
+		}
+	}
+	delta.Sample = samples
+	delta = delta.Compact()
+
+	delta.TimeNanos = base.TimeNanos
+	delta.DurationNanos = current.TimeNanos - base.TimeNanos
+
+	buffer := new(bytes.Buffer)
+	if err := delta.Write(buffer); err != nil {
+		return nil, fmt.Errorf("failed to write delta profile: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
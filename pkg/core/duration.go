@@ -0,0 +1,120 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonDuration 解析JSON中的时长，支持 "30m" 这样的字符串和纳秒数值
+type jsonDuration time.Duration
+
+// UnmarshalJSON 解析字符串或数值形式的时长
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = jsonDuration(parsed)
+	case float64:
+		*d = jsonDuration(time.Duration(v))
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+	return nil
+}
+
+// MarshalJSON 将时长编码为 "30m0s" 这样的字符串
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// plainTask 是没有JSON方法的ProfilingTask，避免编解码时递归
+type plainTask ProfilingTask
+
+// taskJSON 是任务的JSON表示，时长字段以字符串编码
+type taskJSON struct {
+	*plainTask
+	TTL        jsonDuration `json:"ttl,omitempty"`
+	Window     jsonDuration `json:"window,omitempty"`
+	MinLatency jsonDuration `json:"min_latency,omitempty"`
+	MaxLatency jsonDuration `json:"max_latency,omitempty"`
+}
+
+// newTaskJSON 创建任务的JSON表示
+func newTaskJSON(task *ProfilingTask) taskJSON {
+	return taskJSON{
+		plainTask:  (*plainTask)(task),
+		TTL:        jsonDuration(task.TTL),
+		Window:     jsonDuration(task.Window),
+		MinLatency: jsonDuration(task.MinLatency),
+		MaxLatency: jsonDuration(task.MaxLatency),
+	}
+}
+
+// apply 将解析出的时长写回任务
+func (aux taskJSON) apply() {
+	aux.plainTask.TTL = time.Duration(aux.TTL)
+	aux.plainTask.Window = time.Duration(aux.Window)
+	aux.plainTask.MinLatency = time.Duration(aux.MinLatency)
+	aux.plainTask.MaxLatency = time.Duration(aux.MaxLatency)
+}
+
+// MarshalJSON 编码任务，时长字段输出为 "30m0s" 这样的字符串，与UnmarshalJSON对称
+func (task ProfilingTask) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newTaskJSON(&task))
+}
+
+// UnmarshalJSON 解析任务JSON，时长字段同时支持 "30m" 这样的字符串和纳秒数值
+func (task *ProfilingTask) UnmarshalJSON(data []byte) error {
+	aux := taskJSON{plainTask: (*plainTask)(task)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	aux.apply()
+	return nil
+}
+
+// taskStatusJSON 是TaskStatus的JSON表示。TaskStatus嵌入了ProfilingTask，
+// 需要自行编解码，否则提升的任务JSON方法会丢弃配额字段
+type taskStatusJSON struct {
+	taskJSON
+	Captures      int64           `json:"captures"`
+	CapturedBytes int64           `json:"captured_bytes"`
+	Exhausted     bool            `json:"exhausted"`
+	NextWindow    *ScheduleWindow `json:"next_window,omitempty"`
+}
+
+// MarshalJSON 编码任务状态，任务字段与ProfilingTask的编码相同
+func (s TaskStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(taskStatusJSON{
+		taskJSON:      newTaskJSON(&s.ProfilingTask),
+		Captures:      s.Captures,
+		CapturedBytes: s.CapturedBytes,
+		Exhausted:     s.Exhausted,
+		NextWindow:    s.NextWindow,
+	})
+}
+
+// UnmarshalJSON 解析任务状态
+func (s *TaskStatus) UnmarshalJSON(data []byte) error {
+	aux := taskStatusJSON{taskJSON: taskJSON{plainTask: (*plainTask)(&s.ProfilingTask)}}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	aux.apply()
+	s.Captures = aux.Captures
+	s.CapturedBytes = aux.CapturedBytes
+	s.Exhausted = aux.Exhausted
+	s.NextWindow = aux.NextWindow
+	return nil
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfilingTaskJSONRoundTrip(t *testing.T) {
+	task := ProfilingTask{
+		ID:          "orders",
+		Path:        "/api/orders",
+		Methods:     []string{"GET"},
+		ProfileType: "cpu",
+		TTL:         30 * time.Minute,
+		Schedule:    "0 2 * * *",
+		Window:      time.Hour,
+		MinLatency:  250 * time.Millisecond,
+		MaxLatency:  2 * time.Second,
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"ttl":"30m0s"`, `"window":"1h0m0s"`, `"min_latency":"250ms"`, `"max_latency":"2s"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("encoded task %s does not contain %s", data, field)
+		}
+	}
+
+	var decoded ProfilingTask
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, task) {
+		t.Fatalf("round trip = %+v, want %+v", decoded, task)
+	}
+}
+
+func TestProfilingTaskJSONDurations(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want time.Duration
+	}{
+		{"string", `{"ttl":"30m"}`, 30 * time.Minute},
+		{"nanoseconds", `{"ttl":1800000000000}`, 30 * time.Minute},
+		{"null", `{"ttl":null}`, 0},
+		{"missing", `{}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var task ProfilingTask
+			if err := json.Unmarshal([]byte(tt.json), &task); err != nil {
+				t.Fatal(err)
+			}
+			if task.TTL != tt.want {
+				t.Fatalf("TTL = %v, want %v", task.TTL, tt.want)
+			}
+		})
+	}
+
+	var task ProfilingTask
+	if err := json.Unmarshal([]byte(`{"ttl":"soon"}`), &task); err == nil {
+		t.Fatal("invalid duration was accepted")
+	}
+
+	// Zero durations are omitted
+	data, err := json.Marshal(ProfilingTask{Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"ttl", "window", "min_latency", "max_latency"} {
+		if strings.Contains(string(data), `"`+field+`"`) {
+			t.Errorf("encoded task %s contains zero %s", data, field)
+		}
+	}
+}
+
+func TestTaskStatusJSON(t *testing.T) {
+	status := TaskStatus{
+		ProfilingTask: ProfilingTask{ID: "orders", Path: "/api/orders", TTL: time.Hour},
+		Captures:      3,
+		CapturedBytes: 4096,
+		Exhausted:     true,
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"id":"orders"`, `"ttl":"1h0m0s"`, `"captures":3`, `"captured_bytes":4096`, `"exhausted":true`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("encoded status %s does not contain %s", data, field)
+		}
+	}
+
+	var decoded TaskStatus
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, status) {
+		t.Fatalf("round trip = %+v, want %+v", decoded, status)
+	}
+}
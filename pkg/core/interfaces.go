@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -19,6 +20,8 @@ type HTTPContext interface {
 	GetContext(key interface{}) interface{}
 	// GetRequestPath 返回实际请求路径
 	GetRequestPath() string
+	// GetQuery 返回查询参数的值
+	GetQuery(key string) string
 }
 
 // ConfigProvider 抽象性能分析任务的配置源
@@ -31,7 +34,8 @@ type ConfigProvider interface {
 	Close() error
 }
 
-// Storage 抽象性能分析文件的存储后端
+// Storage 抽象性能分析文件的存储后端。压缩等附加行为通过包装Storage的装饰器组合，
+// 装饰器应同时转发StorageReader，使读取对调用方透明
 type Storage interface {
 	// Save 将性能分析数据保存到存储
 	Save(ctx context.Context, filename string, data []byte) error
@@ -43,6 +47,22 @@ type Storage interface {
 	Clean(ctx context.Context, maxAge time.Duration) error
 }
 
+// StorageReader 由能够读回已保存文件的存储实现，文件不存在时返回的错误满足errors.Is(err, fs.ErrNotExist)
+type StorageReader interface {
+	// Open 打开文件用于读取，调用方负责关闭
+	Open(ctx context.Context, filename string) (io.ReadCloser, error)
+	// Stat 返回文件的大小、修改时间和元数据
+	Stat(ctx context.Context, filename string) (FileInfo, error)
+}
+
+// EncodingStorage 由保存时可能压缩文件的存储实现（如storage.CompressingStorage），
+// Manager通过它把实际使用的压缩编码和存储大小写入元数据记录
+type EncodingStorage interface {
+	// SaveEncoded 保存文件，返回使用的压缩编码（如 "gzip"，未压缩时为空字符串）
+	// 和文件在存储中占用的字节数
+	SaveEncoded(ctx context.Context, filename string, data []byte) (encoding string, storedSize int64, err error)
+}
+
 // Logger 抽象日志功能
 type Logger interface {
 	// Info 记录信息消息
@@ -63,6 +83,9 @@ type PathMatcher interface {
 	ExtractParams(template, actual string) map[string]string
 }
 
+// RegexPathPrefix 标记按正则表达式匹配的任务路径，如 "regex:^/api/v1/reports(/.*)?$"
+const RegexPathPrefix = "regex:"
+
 // Profiler 抽象不同类型的性能分析
 type Profiler interface {
 	// StartProfiling 使用给定配置开始性能分析
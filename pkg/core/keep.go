@@ -0,0 +1,96 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// KeepOnRules 定义按请求结果保留分析数据的规则，满足任意一条即保留
+type KeepOnRules struct {
+	StatusCodes  []int    `yaml:"status_codes,omitempty" json:"status_codes,omitempty"`   // 指定状态码，如 [429, 503]
+	StatusRanges []string `yaml:"status_ranges,omitempty" json:"status_ranges,omitempty"` // 状态码范围，如 "5xx" 或 "400-499"
+	HasErrors    bool     `yaml:"has_errors,omitempty" json:"has_errors,omitempty"`       // 处理过程中附加了错误
+}
+
+// RequestOutcome 描述被分析请求的处理结果
+type RequestOutcome struct {
+	StatusCode  int    // 最终响应状态码
+	HasErrors   bool   // 处理过程中是否附加了错误
+	Panicked    bool   // 处理函数是否发生panic
+	RequestPath string // 实际请求路径，用于记录元数据
+}
+
+// IsEmpty 检查是否未配置任何规则
+func (r *KeepOnRules) IsEmpty() bool {
+	return r == nil || (len(r.StatusCodes) == 0 && len(r.StatusRanges) == 0 && !r.HasErrors)
+}
+
+// Matches 检查请求结果是否满足任意一条保留规则，未配置规则时始终满足
+func (r *KeepOnRules) Matches(outcome RequestOutcome) bool {
+	if r.IsEmpty() {
+		return true
+	}
+
+	if r.HasErrors && outcome.HasErrors {
+		return true
+	}
+
+	for _, code := range r.StatusCodes {
+		if code == outcome.StatusCode {
+			return true
+		}
+	}
+
+	for _, statusRange := range r.StatusRanges {
+		low, high, ok := ParseStatusRange(statusRange)
+		if ok && outcome.StatusCode >= low && outcome.StatusCode <= high {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ShouldKeepOutcome 检查请求结果是否满足任务的保留规则
+func (task *ProfilingTask) ShouldKeepOutcome(outcome RequestOutcome) bool {
+	return task.KeepOn.Matches(outcome)
+}
+
+// ParseStatusRange 解析状态码范围，支持 "5xx" 和 "500-599" 两种格式
+func ParseStatusRange(value string) (low, high int, ok bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if len(value) == 3 && strings.HasSuffix(value, "xx") {
+		class, err := strconv.Atoi(value[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, false
+		}
+		return class * 100, class*100 + 99, true
+	}
+
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	low, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, false
+	}
+	high, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || low > high {
+		return 0, 0, false
+	}
+
+	return low, high, true
+}
+
+// PanicOutcome 返回处理函数panic时的请求结果，gin.Recovery随后会返回500
+func PanicOutcome() RequestOutcome {
+	return RequestOutcome{
+		StatusCode: http.StatusInternalServerError,
+		HasErrors:  true,
+		Panicked:   true,
+	}
+}
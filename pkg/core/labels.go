@@ -0,0 +1,43 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"runtime/pprof"
+	"strconv"
+	"time"
+)
+
+// pprof label keys attached to profiled requests
+const (
+	LabelRoute     = "route"
+	LabelMethod    = "method"
+	LabelTask      = "task"
+	LabelProfileID = "profile_id"
+)
+
+// NewProfileID generates a unique identifier for a profiled request
+func NewProfileID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// ProfileLabels returns the pprof labels for a profiled request
+func ProfileLabels(route, method, taskPath, profileID string) pprof.LabelSet {
+	return pprof.Labels(
+		LabelRoute, route,
+		LabelMethod, method,
+		LabelTask, taskPath,
+		LabelProfileID, profileID,
+	)
+}
+
+// ProfileIDFromContext returns the profile ID label carried by ctx
+func ProfileIDFromContext(ctx context.Context) string {
+	id, _ := pprof.Label(ctx, LabelProfileID)
+	return id
+}
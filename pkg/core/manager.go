@@ -2,8 +2,11 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,15 +14,23 @@ import (
 type Manager struct {
 	mu            sync.RWMutex
 	tasks         map[string]ProfilingTask
+	routes        *routeTrie
 	stats         ProfilingStats
 	options       Options
 	limiter       chan struct{}
-	requestCount  map[string]int64
-	configProvider ConfigProvider
+	samplers      map[string]taskSampler
+	schedules     map[string]*TaskSchedule
+	rejected      []QuarantinedTask
+	totalRequests int64
+	taskStats     map[string]*TaskStats
+	layersMu      sync.Mutex
+	layers        []*configLayer
 	storage       Storage
 	logger        Logger
 	pathMatcher   PathMatcher
 	profilers     map[string]Profiler
+	metadata      *metadataIndex
+	hostname      string
 	cleanupStop   chan struct{}
 	cleanupDone   chan struct{}
 }
@@ -28,14 +39,18 @@ type Manager struct {
 func NewManager(opts Options, configProvider ConfigProvider, storage Storage, logger Logger, pathMatcher PathMatcher) *Manager {
 	m := &Manager{
 		tasks:          make(map[string]ProfilingTask),
+		routes:         newRouteTrie(nil),
 		options:        opts,
 		limiter:        make(chan struct{}, opts.MaxConcurrent),
-		requestCount:   make(map[string]int64),
-		configProvider: configProvider,
+		samplers:       make(map[string]taskSampler),
+		schedules:      make(map[string]*TaskSchedule),
+		taskStats:      make(map[string]*TaskStats),
+		layers:         []*configLayer{newConfigLayer(configProvider, "")},
 		storage:        storage,
 		logger:         logger,
 		pathMatcher:    pathMatcher,
 		profilers:      make(map[string]Profiler),
+		metadata:       newMetadataIndex(),
 		cleanupStop:    make(chan struct{}),
 		cleanupDone:    make(chan struct{}),
 		stats: ProfilingStats{
@@ -43,13 +58,25 @@ func NewManager(opts Options, configProvider ConfigProvider, storage Storage, lo
 		},
 	}
 
+	m.hostname, _ = os.Hostname()
+
 	// 注册默认分析器
 	m.RegisterProfiler(NewCPUProfiler())
 	m.RegisterProfiler(NewHeapProfiler())
+	m.RegisterProfiler(NewHeapDeltaProfiler())
+	m.RegisterProfiler(NewAllocsDeltaProfiler())
 	m.RegisterProfiler(NewGoroutineProfiler())
+	m.RegisterProfiler(NewMutexProfiler())
+	m.RegisterProfiler(NewBlockProfiler())
+	m.RegisterProfiler(NewTraceProfiler())
+
+	// 自定义分析器必须在首次同步配置前注册，否则其类型的任务会被隔离
+	for _, profiler := range opts.Profilers {
+		m.RegisterProfiler(profiler)
+	}
 
 	// 启动后台任务
-	go m.startConfigSync()
+	go m.startConfigSync(m.layers[0])
 	go m.startCleanup()
 
 	logger.Info("Profiling manager initialized", map[string]interface{}{
@@ -66,63 +93,249 @@ func (m *Manager) RegisterProfiler(profiler Profiler) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.profilers[profiler.GetProfileType()] = profiler
+	registerProfileType(profiler.GetProfileType())
 	
 	m.logger.Info("Profiler registered", map[string]interface{}{
 		"type": profiler.GetProfileType(),
 	})
 }
 
-// ShouldProfile 检查是否应该对请求进行性能分析
+// HasProfiler 检查是否已注册指定类型的分析器
+func (m *Manager) HasProfiler(profileType string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, exists := m.profilers[profileType]
+	return exists
+}
+
+// ShouldProfile 检查是否应该对请求进行性能分析，返回第一个命中采样的匹配任务
 func (m *Manager) ShouldProfile(path, method string) (ProfilingTask, bool) {
 	if !m.options.Enabled {
 		return ProfilingTask{}, false
 	}
 
 	m.mu.RLock()
-	defer m.mu.RUnlock()
+	matched := m.matchingTasksLocked(path, method)
+	if len(matched) > 0 {
+		atomic.AddInt64(&m.totalRequests, 1)
+	}
 
-	// 查找匹配的任务
-	var matchedTask ProfilingTask
-	var found bool
+	for _, task := range matched {
+		if !m.sampleLocked(task) {
+			continue
+		}
+		m.mu.RUnlock()
 
-	for _, task := range m.tasks {
-		if m.pathMatcher.Match(task.Path, path) && task.ShouldMatchMethod(method) {
-			matchedTask = task
-			found = true
-			break
+		// 检查并发限制
+		if !m.acquireLimiter(path) {
+			m.recordLimited(task)
+			return ProfilingTask{}, false
 		}
+		return task, true
 	}
 
-	if !found {
+	m.mu.RUnlock()
+	return ProfilingTask{}, false
+}
+
+// ShouldProfileTask 检查是否应该使用指定任务对请求进行性能分析
+func (m *Manager) ShouldProfileTask(taskID, path, method string) (ProfilingTask, bool) {
+	if !m.options.Enabled {
 		return ProfilingTask{}, false
 	}
 
-	// 检查是否已过期
-	if time.Now().After(matchedTask.ExpiresAt) {
+	m.mu.RLock()
+	task, exists := m.tasks[taskID]
+	if !exists || !m.availableLocked(task, time.Now()) {
+		m.mu.RUnlock()
+		return ProfilingTask{}, false
+	}
+	if !m.pathMatcher.Match(task.Path, path) || !task.ShouldMatchMethod(method) {
+		m.mu.RUnlock()
 		return ProfilingTask{}, false
 	}
 
-	// 采样率控制
-	if matchedTask.SampleRate > 1 {
-		m.requestCount[matchedTask.Path]++
-		count := m.requestCount[matchedTask.Path]
+	atomic.AddInt64(&m.totalRequests, 1)
+	m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.MatchedCount, 1) })
+	sampled := m.sampleLocked(task)
+	m.mu.RUnlock()
+	if !sampled {
+		return ProfilingTask{}, false
+	}
+
+	if !m.acquireLimiter(path) {
+		m.recordLimited(task)
+		return ProfilingTask{}, false
+	}
+	return task, true
+}
+
+// recordLimited 记录因并发限制而未能分析的请求
+func (m *Manager) recordLimited(task ProfilingTask) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.FailedCount++
+	m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.FailedCount, 1) })
+}
+
+// MatchingTasks 返回匹配请求的所有未过期任务，按优先级和路由具体程度排序
+func (m *Manager) MatchingTasks(path, method string) []ProfilingTask {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.matchingTasksLocked(path, method)
+}
+
+// matchingTasksLocked 查找匹配的任务，调用方需持有读锁
+func (m *Manager) matchingTasksLocked(path, method string) []ProfilingTask {
+	now := time.Now()
+
+	// 前缀树给出按优先级排序的候选任务，再由路径匹配器确认
+	var matched []ProfilingTask
+	for _, entry := range m.routes.match(path) {
+		task := entry.task
+		if !m.availableLocked(task, now) {
+			continue
+		}
+		if task.ShouldMatchMethod(method) && m.pathMatcher.Match(task.Path, path) {
+			matched = append(matched, task)
+			m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.MatchedCount, 1) })
+		}
+	}
+
+	return matched
+}
+
+// sampleLocked 使用任务的采样器判断本次请求是否需要分析，调用方需持有读锁。
+// 采样器自身是并发安全的，读锁只保护采样器映射
+func (m *Manager) sampleLocked(task ProfilingTask) bool {
+	entry, exists := m.samplers[task.ID]
+	if !exists {
+		return true
+	}
+	return entry.sampler.Sample()
+}
+
+// availableLocked 检查任务当前能否分析：已开始、未过期、未达到配额且处于计划窗口内，调用方需持有读锁
+func (m *Manager) availableLocked(task ProfilingTask, now time.Time) bool {
+	if now.Before(task.StartsAt) || now.After(task.ExpiresAt) || m.exhaustedLocked(task) {
+		return false
+	}
+	if schedule := m.schedules[task.ID]; schedule != nil && !schedule.Active(now) {
+		return false
+	}
+	return true
+}
+
+// exhaustedLocked 检查任务是否已达到保存配额，调用方需持有读锁
+func (m *Manager) exhaustedLocked(task ProfilingTask) bool {
+	stats, exists := m.taskStats[task.ID]
+	if !exists {
+		return false
+	}
+	return task.QuotaExhausted(atomic.LoadInt64(&stats.KeptCount), atomic.LoadInt64(&stats.CapturedBytes))
+}
+
+// recordTaskStat 更新任务统计信息，任务不存在（如按需触发的任务）时忽略
+func (m *Manager) recordTaskStat(taskID string, update func(*TaskStats)) {
+	if stats, exists := m.taskStats[taskID]; exists {
+		update(stats)
+	}
+}
 
-		if count%int64(matchedTask.SampleRate) != 0 {
+// ShouldProfileRequest 检查是否应该对请求进行性能分析。
+// 携带有效按需触发器的请求优先，其余请求按配置任务匹配
+func (m *Manager) ShouldProfileRequest(httpCtx HTTPContext) (ProfilingTask, bool) {
+	if !m.options.Enabled {
+		return ProfilingTask{}, false
+	}
+
+	if task, triggered := m.triggeredTask(httpCtx); triggered {
+		if !m.acquireLimiter(task.Path) {
+			m.mu.Lock()
+			m.stats.FailedCount++
+			m.mu.Unlock()
 			return ProfilingTask{}, false
 		}
+		return task, true
 	}
 
-	// 检查并发限制
+	return m.ShouldProfile(httpCtx.GetPath(), httpCtx.GetMethod())
+}
+
+// triggeredTask 校验按需触发器并为单个请求生成临时任务
+func (m *Manager) triggeredTask(httpCtx HTTPContext) (ProfilingTask, bool) {
+	trigger := m.options.Trigger
+	if !trigger.Enabled {
+		return ProfilingTask{}, false
+	}
+
+	profileType, token := trigger.readTrigger(httpCtx)
+	if profileType == "" {
+		return ProfilingTask{}, false
+	}
+
+	path := httpCtx.GetPath()
+	method := httpCtx.GetMethod()
+	requestPath := httpCtx.GetRequestPath()
+
+	now := time.Now()
+	if !trigger.verifyToken(token, profileType, method, requestPath, now) {
+		m.logger.Warn("Rejected profiling trigger with invalid token", map[string]interface{}{
+			"path":         path,
+			"request_path": requestPath,
+			"type":         profileType,
+		})
+		return ProfilingTask{}, false
+	}
+
+	m.mu.RLock()
+	_, exists := m.profilers[profileType]
+	m.mu.RUnlock()
+	if !exists {
+		m.logger.Warn("Rejected profiling trigger with unknown profile type", map[string]interface{}{
+			"path": path,
+			"type": profileType,
+		})
+		return ProfilingTask{}, false
+	}
+
+	duration := int(trigger.Duration / time.Second)
+	if duration <= 0 {
+		duration = int(m.options.DefaultDuration / time.Second)
+	}
+
+	m.logger.Info("Profiling triggered on demand", map[string]interface{}{
+		"path":         path,
+		"request_path": requestPath,
+		"method":       method,
+		"type":         profileType,
+	})
+
+	return ProfilingTask{
+		Path:        path,
+		Methods:     []string{method},
+		ExpiresAt:   now.Add(time.Duration(duration) * time.Second),
+		Duration:    duration,
+		SampleRate:  1,
+		ProfileType: profileType,
+		Source:      SourceTrigger,
+	}, true
+}
+
+// acquireLimiter 尝试从并发限制器获取一个槽位
+func (m *Manager) acquireLimiter(path string) bool {
 	select {
 	case m.limiter <- struct{}{}:
-		return matchedTask, true
+		return true
 	default:
-		m.stats.FailedCount++
 		m.logger.Warn("Concurrent limit exceeded", map[string]interface{}{
-			"path": path,
+			"path":  path,
 			"limit": m.options.MaxConcurrent,
 		})
-		return ProfilingTask{}, false
+		return false
 	}
 }
 
@@ -141,12 +354,18 @@ func (m *Manager) StartProfiling(ctx context.Context, path string, task Profilin
 		return nil, err
 	}
 
+	// 未配置持续时间时使用默认值，分析时长由会话自身控制
+	if task.Duration <= 0 {
+		task.Duration = int(m.options.DefaultDuration / time.Second)
+	}
+
 	// 开始性能分析会话
 	session, err := profiler.StartProfiling(ctx, task)
 	if err != nil {
 		m.releaseLimiter()
 		m.mu.Lock()
 		m.stats.FailedCount++
+		m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.FailedCount, 1) })
 		m.mu.Unlock()
 		
 		m.logger.Error("Failed to start profiling", map[string]interface{}{
@@ -160,9 +379,11 @@ func (m *Manager) StartProfiling(ctx context.Context, path string, task Profilin
 	m.mu.Lock()
 	m.stats.ActiveProfiles++
 	m.stats.ProfiledCount++
+	m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.ProfiledCount, 1) })
 	m.mu.Unlock()
 
 	m.logger.Info("Profiling started", map[string]interface{}{
+		"task_id":  task.ID,
 		"path":     path,
 		"type":     task.ProfileType,
 		"duration": task.Duration,
@@ -171,8 +392,8 @@ func (m *Manager) StartProfiling(ctx context.Context, path string, task Profilin
 	return session, nil
 }
 
-// StopProfiling 停止性能分析会话并保存结果
-func (m *Manager) StopProfiling(ctx context.Context, path, method string, task ProfilingTask, session ProfileSession) (*ProfilingResult, error) {
+// StopProfiling 停止性能分析会话，并在请求结果满足任务保留条件时保存结果
+func (m *Manager) StopProfiling(ctx context.Context, path, method string, task ProfilingTask, session ProfileSession, outcome RequestOutcome) (*ProfilingResult, error) {
 	defer m.releaseLimiter()
 
 	startTime := session.GetStartTime()
@@ -183,17 +404,32 @@ func (m *Manager) StopProfiling(ctx context.Context, path, method string, task P
 	m.mu.Unlock()
 
 	result := &ProfilingResult{
+		ProfileID:   ProfileIDFromContext(ctx),
+		TaskID:      task.ID,
+		Source:      task.Source,
 		Path:        path,
+		Method:      method,
+		RequestPath: outcome.RequestPath,
+		Hostname:    m.hostname,
 		StartTime:   startTime,
 		Duration:    time.Since(startTime),
 		ProfileType: task.ProfileType,
 		Success:     err == nil,
+		StatusCode:  outcome.StatusCode,
+		HasErrors:   outcome.HasErrors,
+		Panicked:    outcome.Panicked,
+	}
+	if outcome.RequestPath != "" && m.pathMatcher != nil {
+		if params := m.pathMatcher.ExtractParams(path, outcome.RequestPath); len(params) > 0 {
+			result.PathParams = params
+		}
 	}
 
 	if err != nil {
 		result.Error = err.Error()
 		m.mu.Lock()
 		m.stats.FailedCount++
+		m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.FailedCount, 1) })
 		m.mu.Unlock()
 		
 		m.logger.Error("Failed to stop profiling", map[string]interface{}{
@@ -203,6 +439,24 @@ func (m *Manager) StopProfiling(ctx context.Context, path, method string, task P
 		return result, err
 	}
 
+	// 尾部分析：请求耗时或结果不满足保留条件时丢弃数据，不写入存储
+	if !task.ShouldKeepLatency(result.Duration) || !task.ShouldKeepOutcome(outcome) {
+		result.Discarded = true
+		m.mu.Lock()
+		m.stats.DiscardedCount++
+		m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.DiscardedCount, 1) })
+		m.mu.Unlock()
+
+		m.logger.Debug("Profile discarded by retention rules", map[string]interface{}{
+			"path":        path,
+			"type":        task.ProfileType,
+			"latency_ms":  result.Duration.Milliseconds(),
+			"status_code": outcome.StatusCode,
+			"has_errors":  outcome.HasErrors,
+		})
+		return result, nil
+	}
+
 	if len(data) == 0 {
 		m.logger.Warn("Empty profiling data", map[string]interface{}{
 			"path": path,
@@ -212,16 +466,18 @@ func (m *Manager) StopProfiling(ctx context.Context, path, method string, task P
 	}
 
 	// 生成文件名
-	filename := m.generateFilename(path, method, task.ProfileType)
+	filename := m.generateFilename(path, method, task.ProfileType, result.ProfileID)
 	result.Filename = filename
 	result.FileSize = int64(len(data))
 
 	// 保存到存储
-	if err := m.storage.Save(ctx, filename, data); err != nil {
+	encoding, storedSize, err := m.saveProfile(ctx, filename, data)
+	if err != nil {
 		result.Success = false
 		result.Error = err.Error()
 		m.mu.Lock()
 		m.stats.FailedCount++
+		m.recordTaskStat(task.ID, func(s *TaskStats) { atomic.AddInt64(&s.FailedCount, 1) })
 		m.mu.Unlock()
 		
 		m.logger.Error("Failed to save profile", map[string]interface{}{
@@ -232,8 +488,34 @@ func (m *Manager) StopProfiling(ctx context.Context, path, method string, task P
 		return result, err
 	}
 
+	result.ContentEncoding = encoding
+	result.StoredSize = storedSize
+
+	var captures, capturedBytes int64
+	m.mu.Lock()
+	m.stats.KeptCount++
+	m.recordTaskStat(task.ID, func(s *TaskStats) {
+		captures = atomic.AddInt64(&s.KeptCount, 1)
+		capturedBytes = atomic.AddInt64(&s.CapturedBytes, result.FileSize)
+	})
+	m.mu.Unlock()
+
+	// 并发会话可能使配额略微超出，只在首次达到配额时记录
+	if task.QuotaExhausted(captures, capturedBytes) && !task.QuotaExhausted(captures-1, capturedBytes-result.FileSize) {
+		m.logger.Info("Task quota exhausted", map[string]interface{}{
+			"task_id":        task.ID,
+			"path":           path,
+			"captures":       captures,
+			"captured_bytes": capturedBytes,
+		})
+	}
+
+	// 元数据记录保存失败不影响已保存的分析文件
+	m.saveMetadata(ctx, result)
+
 	m.logger.Info("Profiling completed", map[string]interface{}{
 		"path":        path,
+		"profile_id":  result.ProfileID,
 		"filename":    filename,
 		"duration_ms": result.Duration.Milliseconds(),
 		"file_size":   result.FileSize,
@@ -243,23 +525,127 @@ func (m *Manager) StopProfiling(ctx context.Context, path, method string, task P
 	return result, nil
 }
 
+// saveProfile 保存分析文件，返回使用的压缩编码和存储大小；
+// 存储未实现EncodingStorage时文件按原样保存
+func (m *Manager) saveProfile(ctx context.Context, filename string, data []byte) (string, int64, error) {
+	if encoder, ok := m.storage.(EncodingStorage); ok {
+		return encoder.SaveEncoded(ctx, filename, data)
+	}
+	return "", int64(len(data)), m.storage.Save(ctx, filename, data)
+}
+
+// saveMetadata 将分析结果作为JSON元数据记录保存在分析文件旁
+func (m *Manager) saveMetadata(ctx context.Context, result *ProfilingResult) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		m.logger.Error("Failed to encode profile metadata", map[string]interface{}{
+			"filename": result.Filename,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	metadataFile := MetadataFilename(result.Filename)
+	if err := m.storage.Save(ctx, metadataFile, data); err != nil {
+		m.logger.Error("Failed to save profile metadata", map[string]interface{}{
+			"filename": metadataFile,
+			"error":    err.Error(),
+		})
+		return
+	}
+	m.metadata.add(metadataFile, *result)
+}
+
+// QueryProfiles 按条件查询已保存分析文件的元数据，结果按开始时间从新到旧排序。
+// 存储未实现StorageReader时返回ErrStorageNotReadable
+func (m *Manager) QueryProfiles(ctx context.Context, query ProfileQuery) ([]ProfilingResult, error) {
+	return m.metadata.query(ctx, m.storage, query)
+}
+
 // GetStats 返回当前统计信息
 func (m *Manager) GetStats() ProfilingStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// 计算总请求数
-	var totalRequests int64
-	for _, count := range m.requestCount {
-		totalRequests += count
-	}
-	
 	stats := m.stats
-	stats.TotalRequests = totalRequests
+	stats.TotalRequests = atomic.LoadInt64(&m.totalRequests)
 	return stats
 }
 
-// GetTasks 返回当前任务
+// GetTaskStats 返回每个任务的统计信息，以任务ID为键
+func (m *Manager) GetTaskStats() map[string]TaskStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[string]TaskStats, len(m.taskStats))
+	for id, s := range m.taskStats {
+		stats[id] = TaskStats{
+			TaskID:         s.TaskID,
+			Path:           s.Path,
+			ProfileType:    s.ProfileType,
+			Source:         s.Source,
+			MatchedCount:   atomic.LoadInt64(&s.MatchedCount),
+			ProfiledCount:  atomic.LoadInt64(&s.ProfiledCount),
+			FailedCount:    atomic.LoadInt64(&s.FailedCount),
+			KeptCount:      atomic.LoadInt64(&s.KeptCount),
+			DiscardedCount: atomic.LoadInt64(&s.DiscardedCount),
+			CapturedBytes:  atomic.LoadInt64(&s.CapturedBytes),
+		}
+	}
+	return stats
+}
+
+// GetTaskStatuses 返回当前任务及其配额使用情况，以任务ID为键
+func (m *Manager) GetTaskStatuses() map[string]TaskStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	statuses := make(map[string]TaskStatus, len(m.tasks))
+	for id, task := range m.tasks {
+		status := TaskStatus{ProfilingTask: task}
+		if stats, exists := m.taskStats[id]; exists {
+			status.Captures = atomic.LoadInt64(&stats.KeptCount)
+			status.CapturedBytes = atomic.LoadInt64(&stats.CapturedBytes)
+		}
+		status.Exhausted = task.QuotaExhausted(status.Captures, status.CapturedBytes)
+		if schedule := m.schedules[id]; schedule != nil {
+			window := schedule.Window(now)
+			status.NextWindow = &window
+		}
+		statuses[id] = status
+	}
+	return statuses
+}
+
+// GetQuarantinedTasks 返回因配置无效而被拒绝的任务，包括配置提供器隔离的任务
+// 和未经提供器校验、被管理器拒绝的任务
+func (m *Manager) GetQuarantinedTasks() []QuarantinedTask {
+	m.layersMu.Lock()
+	layers := m.layers
+	m.layersMu.Unlock()
+
+	var quarantined []QuarantinedTask
+	for _, layer := range layers {
+		reporter, ok := layer.provider.(TaskQuarantine)
+		if !ok {
+			continue
+		}
+		for _, entry := range reporter.QuarantinedTasks() {
+			if entry.Task.Source == "" {
+				entry.Task.Source = layer.source
+			}
+			quarantined = append(quarantined, entry)
+		}
+	}
+
+	m.mu.RLock()
+	quarantined = append(quarantined, m.rejected...)
+	m.mu.RUnlock()
+	return quarantined
+}
+
+// GetTasks 返回当前任务，以任务ID为键
 func (m *Manager) GetTasks() map[string]ProfilingTask {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -281,29 +667,35 @@ func (m *Manager) Close() error {
 	close(m.cleanupStop)
 	<-m.cleanupDone
 
-	if m.configProvider != nil {
-		m.configProvider.Close()
+	m.layersMu.Lock()
+	layers := m.layers
+	m.layersMu.Unlock()
+	for _, layer := range layers {
+		if layer.provider != nil {
+			layer.provider.Close()
+		}
 	}
 
 	m.logger.Info("Profiling manager closed", nil)
 	return nil
 }
 
-// startConfigSync 启动配置同步
-func (m *Manager) startConfigSync() {
+// startConfigSync 启动配置层的同步
+func (m *Manager) startConfigSync(layer *configLayer) {
 	// 初始加载
 	ctx := context.Background()
-	if tasks, err := m.configProvider.GetTasks(ctx); err == nil {
-		m.updateTasks(tasks)
+	if tasks, err := layer.provider.GetTasks(ctx); err == nil {
+		m.updateLayer(layer, tasks)
 	} else {
 		m.logger.Error("Failed to load initial config", map[string]interface{}{
-			"error": err.Error(),
+			"source": layer.source,
+			"error":  err.Error(),
 		})
 	}
 
 	// 订阅变更
-	m.configProvider.Subscribe(ctx, func(tasks []ProfilingTask) {
-		m.updateTasks(tasks)
+	layer.provider.Subscribe(ctx, func(tasks []ProfilingTask) {
+		m.updateLayer(layer, tasks)
 	})
 }
 
@@ -323,6 +715,7 @@ func (m *Manager) startCleanup() {
 					"error": err.Error(),
 				})
 			}
+			m.applyRetention(ctx)
 			m.cleanExpiredTasks()
 		case <-m.cleanupStop:
 			return
@@ -335,19 +728,81 @@ func (m *Manager) updateTasks(newTasks []ProfilingTask) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 转换为map以便查找
+	// 以任务ID为键，同一路由可以配置多个任务
 	taskMap := make(map[string]ProfilingTask)
+	schedules := make(map[string]*TaskSchedule)
+	var rejected []QuarantinedTask
 	for _, task := range newTasks {
-		taskMap[task.Path] = task
+		if task.ID == "" {
+			task.ID = task.GenerateID()
+		}
+
+		// 内置配置提供器已隔离无效任务，这里拒绝其他提供器传入的无效任务
+		if err := task.Validate(); err != nil {
+			rejected = append(rejected, newQuarantinedTask(task, err, time.Now()))
+			m.logger.Error("Invalid task, task ignored", map[string]interface{}{
+				"id":    task.ID,
+				"path":  task.Path,
+				"error": err.Error(),
+			})
+			continue
+		}
+		schedule, _ := NewTaskSchedule(task)
+
+		if _, exists := taskMap[task.ID]; exists {
+			// 生成的ID重复（完全相同的任务）时追加序号
+			base := task.ID
+			for i := 2; ; i++ {
+				task.ID = fmt.Sprintf("%s-%d", base, i)
+				if _, exists := taskMap[task.ID]; !exists {
+					break
+				}
+			}
+			m.logger.Warn("Duplicate task id", map[string]interface{}{
+				"id":   base,
+				"path": task.Path,
+			})
+		}
+		taskMap[task.ID] = task
+		if schedule != nil {
+			schedules[task.ID] = schedule
+		}
 	}
 
 	m.tasks = taskMap
+	m.schedules = schedules
+	m.rejected = rejected
+	m.routes = newRouteTrie(taskMap)
 	m.stats.LastUpdate = time.Now()
 
-	// 清理已删除任务的请求计数
-	for path := range m.requestCount {
-		if _, exists := taskMap[path]; !exists {
-			delete(m.requestCount, path)
+	// 保留已有任务的统计信息，为新任务创建统计
+	for id, task := range taskMap {
+		stats, exists := m.taskStats[id]
+		if !exists {
+			stats = &TaskStats{TaskID: id}
+			m.taskStats[id] = stats
+		}
+		stats.Path = task.Path
+		stats.ProfileType = task.ProfileType
+		stats.Source = task.Source
+	}
+
+	// 采样配置未变化的任务沿用原采样器，避免重新加载时重置计数和名额
+	samplers := make(map[string]taskSampler, len(taskMap))
+	for id, task := range taskMap {
+		config := newSamplerConfig(task)
+		if existing, exists := m.samplers[id]; exists && existing.config == config {
+			samplers[id] = existing
+			continue
+		}
+		samplers[id] = taskSampler{config: config, sampler: NewSampler(task)}
+	}
+	m.samplers = samplers
+
+	// 清理已删除任务的统计
+	for id := range m.taskStats {
+		if _, exists := taskMap[id]; !exists {
+			delete(m.taskStats, id)
 		}
 	}
 
@@ -362,12 +817,20 @@ func (m *Manager) cleanExpiredTasks() {
 	defer m.mu.Unlock()
 
 	now := time.Now()
-	for path, task := range m.tasks {
+	removed := 0
+	for id, task := range m.tasks {
 		if now.After(task.ExpiresAt) {
-			delete(m.tasks, path)
-			delete(m.requestCount, path)
+			delete(m.tasks, id)
+			delete(m.samplers, id)
+			delete(m.schedules, id)
+			delete(m.taskStats, id)
+			removed++
 		}
 	}
+
+	if removed > 0 {
+		m.routes = newRouteTrie(m.tasks)
+	}
 }
 
 // releaseLimiter 从并发限制器释放一个槽位
@@ -378,13 +841,26 @@ func (m *Manager) releaseLimiter() {
 	}
 }
 
-// generateFilename 为性能分析文件生成文件名
-func (m *Manager) generateFilename(path, method, profileType string) string {
+// generateFilename 为性能分析文件生成文件名，存在分析ID时以其作为唯一后缀
+func (m *Manager) generateFilename(path, method, profileType, profileID string) string {
 	sanitized := sanitizePath(path)
 	timestamp := time.Now().Format("20060102_150405")
-	nanos := time.Now().UnixNano() % 1000000
-	
-	return fmt.Sprintf("%s/profile_%s_%s_%s_%d.pprof", profileType, sanitized, method, timestamp, nanos)
+	suffix := profileID
+	if suffix == "" {
+		suffix = fmt.Sprintf("%d", time.Now().UnixNano()%1000000)
+	}
+
+	return fmt.Sprintf("%s/profile_%s_%s_%s_%s%s", profileType, sanitized, method, timestamp, suffix, fileExtension(profileType))
+}
+
+// fileExtension 返回分析类型对应的文件扩展名
+func fileExtension(profileType string) string {
+	switch profileType {
+	case "trace":
+		return ".trace"
+	default:
+		return ".pprof"
+	}
 }
 
 // sanitizePath 清理路径以便在文件名中使用
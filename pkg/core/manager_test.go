@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nopLogger discards log messages
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, fields map[string]interface{})  {}
+func (nopLogger) Warn(msg string, fields map[string]interface{})  {}
+func (nopLogger) Error(msg string, fields map[string]interface{}) {}
+func (nopLogger) Debug(msg string, fields map[string]interface{}) {}
+
+// staticProvider is a custom config provider that returns fixed tasks
+type staticProvider struct {
+	tasks []ProfilingTask
+}
+
+func (p *staticProvider) GetTasks(ctx context.Context) ([]ProfilingTask, error) {
+	return p.tasks, nil
+}
+
+func (p *staticProvider) Subscribe(ctx context.Context, callback func([]ProfilingTask)) error {
+	return nil
+}
+
+func (p *staticProvider) Close() error { return nil }
+
+// nopStorage discards profiles
+type nopStorage struct{}
+
+func (nopStorage) Save(ctx context.Context, filename string, data []byte) error { return nil }
+func (nopStorage) List(ctx context.Context, pattern string) ([]string, error)   { return nil, nil }
+func (nopStorage) Delete(ctx context.Context, filename string) error            { return nil }
+func (nopStorage) Clean(ctx context.Context, maxAge time.Duration) error        { return nil }
+
+// exactMatcher matches task paths literally
+type exactMatcher struct{}
+
+func (exactMatcher) Match(template, actual string) bool { return template == actual }
+func (exactMatcher) ExtractParams(template, actual string) map[string]string {
+	return map[string]string{}
+}
+
+// newTestManager creates a manager with provider and waits until its
+// initial tasks are loaded
+func newTestManager(t *testing.T, provider ConfigProvider, storage Storage, opts Options) *Manager {
+	t.Helper()
+	m := NewManager(opts, provider, storage, nopLogger{}, exactMatcher{})
+	t.Cleanup(func() { m.Close() })
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m.mu.RLock()
+		updated := !m.stats.LastUpdate.IsZero() && (len(m.tasks) > 0 || len(m.rejected) > 0)
+		m.mu.RUnlock()
+		if updated {
+			return m
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("initial tasks were not loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManagerResolvesTTLOfCustomProviderTasks(t *testing.T) {
+	provider := &staticProvider{tasks: []ProfilingTask{{
+		ID:          "custom",
+		Path:        "/api/orders",
+		Methods:     []string{"GET"},
+		ProfileType: "cpu",
+		Duration:    10,
+		SampleRate:  1,
+		TTL:         30 * time.Minute,
+	}}}
+	before := time.Now()
+	m := newTestManager(t, provider, nopStorage{}, DefaultOptions())
+
+	task, exists := m.GetTasks()["custom"]
+	if !exists {
+		t.Fatalf("ttl-only task was rejected: %v", m.GetQuarantinedTasks())
+	}
+	if want := before.Add(30 * time.Minute); task.ExpiresAt.Before(want) || task.ExpiresAt.After(want.Add(time.Minute)) {
+		t.Fatalf("ExpiresAt = %v, want about %v", task.ExpiresAt, want)
+	}
+	if matched := m.MatchingTasks("/api/orders", "GET"); len(matched) != 1 {
+		t.Fatalf("MatchingTasks returned %d tasks, want 1", len(matched))
+	}
+
+	// A reload keeps the first-seen time instead of extending the ttl
+	m.startConfigSync(m.layers[0])
+	if reloaded := m.GetTasks()["custom"]; !reloaded.ExpiresAt.Equal(task.ExpiresAt) {
+		t.Fatalf("reload moved ExpiresAt from %v to %v", task.ExpiresAt, reloaded.ExpiresAt)
+	}
+}
+
+// encodingStorage keeps saved files in memory and reports every ".trace"
+// file as compressed with zstd
+type encodingStorage struct {
+	nopStorage
+	files map[string][]byte
+}
+
+func (s *encodingStorage) Save(ctx context.Context, filename string, data []byte) error {
+	_, _, err := s.SaveEncoded(ctx, filename, data)
+	return err
+}
+
+func (s *encodingStorage) SaveEncoded(ctx context.Context, filename string, data []byte) (string, int64, error) {
+	s.files[filename] = data
+	if strings.HasSuffix(filename, ".trace") {
+		return "zstd", 3, nil
+	}
+	return "", int64(len(data)), nil
+}
+
+// fakeSession returns fixed profile data
+type fakeSession struct {
+	data []byte
+}
+
+func (s fakeSession) Stop() ([]byte, error)   { return s.data, nil }
+func (s fakeSession) GetStartTime() time.Time { return time.Now() }
+func (s fakeSession) IsRunning() bool         { return true }
+
+func TestManagerRecordsContentEncoding(t *testing.T) {
+	task := ProfilingTask{
+		ID:          "trace",
+		Path:        "/api/orders",
+		Methods:     []string{"GET"},
+		ProfileType: "trace",
+		Duration:    10,
+		SampleRate:  1,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	storage := &encodingStorage{files: make(map[string][]byte)}
+	m := newTestManager(t, &staticProvider{tasks: []ProfilingTask{task}}, storage, DefaultOptions())
+
+	result, err := m.StopProfiling(context.Background(), task.Path, "GET", task, fakeSession{data: []byte("trace data")}, RequestOutcome{StatusCode: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentEncoding != "zstd" || result.StoredSize != 3 || result.FileSize != 10 {
+		t.Fatalf("ContentEncoding = %q, StoredSize = %d, FileSize = %d", result.ContentEncoding, result.StoredSize, result.FileSize)
+	}
+
+	var record ProfilingResult
+	if err := json.Unmarshal(storage.files[MetadataFilename(result.Filename)], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.ContentEncoding != "zstd" || record.StoredSize != 3 {
+		t.Fatalf("metadata record has content encoding %q and stored size %d", record.ContentEncoding, record.StoredSize)
+	}
+}
+
+// customProfiler is a profiler for a custom profile type
+type customProfiler struct {
+	profileType string
+}
+
+func (p customProfiler) StartProfiling(ctx context.Context, task ProfilingTask) (ProfileSession, error) {
+	return fakeSession{data: []byte(p.profileType)}, nil
+}
+
+func (p customProfiler) GetProfileType() string { return p.profileType }
+
+func TestManagerRegistersProfilersBeforeConfigSync(t *testing.T) {
+	task := validTask("/api/orders", "options_custom")
+	task.ID = "custom"
+
+	opts := DefaultOptions()
+	opts.Profilers = []Profiler{customProfiler{profileType: "options_custom"}}
+	m := newTestManager(t, &staticProvider{tasks: []ProfilingTask{task}}, nopStorage{}, opts)
+
+	if _, exists := m.GetTasks()["custom"]; !exists {
+		t.Fatalf("task with a custom profile type was quarantined: %v", m.GetQuarantinedTasks())
+	}
+	if !m.HasProfiler("options_custom") {
+		t.Fatal("custom profiler is not registered")
+	}
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetadataExtension 是分析文件旁元数据记录的扩展名，
+// 如 "cpu/profile_x.pprof" 的元数据保存在 "cpu/profile_x.pprof.json"
+const MetadataExtension = ".json"
+
+// ErrStorageNotReadable 表示存储未实现StorageReader，无法查询元数据
+var ErrStorageNotReadable = errors.New("storage does not support reading profiles")
+
+// MetadataFilename 返回分析文件对应的元数据记录文件名
+func MetadataFilename(filename string) string {
+	return filename + MetadataExtension
+}
+
+// ProfileQuery 定义分析文件元数据的查询条件，零值字段不参与过滤
+type ProfileQuery struct {
+	ProfileID   string        // 分析ID，如触发请求响应头X-Gin-Pprof-Id的值
+	Route       string        // 路由模板，精确匹配，如 "/api/users/:id"
+	ProfileType string        // 分析类型
+	TaskID      string        // 任务ID
+	Since       time.Time     // 开始时间不早于Since
+	Until       time.Time     // 开始时间早于Until
+	MinLatency  time.Duration // 请求耗时下限
+	MaxLatency  time.Duration // 请求耗时上限
+	Status      string        // 状态码，如 "500"、"5xx" 或 "500-599"
+	Limit       int           // 最多返回的记录数，0表示不限制
+}
+
+// ParseStatus 解析查询的状态码条件，支持单个状态码和ParseStatusRange的格式
+func ParseStatus(value string) (low, high int, ok bool) {
+	if code, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return code, code, true
+	}
+	return ParseStatusRange(value)
+}
+
+// Matches 检查元数据记录是否满足查询条件
+func (q ProfileQuery) Matches(result ProfilingResult) bool {
+	if q.ProfileID != "" && result.ProfileID != q.ProfileID {
+		return false
+	}
+	if q.Route != "" && result.Path != q.Route {
+		return false
+	}
+	if q.ProfileType != "" && result.ProfileType != q.ProfileType {
+		return false
+	}
+	if q.TaskID != "" && result.TaskID != q.TaskID {
+		return false
+	}
+	if !q.Since.IsZero() && result.StartTime.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !result.StartTime.Before(q.Until) {
+		return false
+	}
+	if q.MinLatency > 0 && result.Duration < q.MinLatency {
+		return false
+	}
+	if q.MaxLatency > 0 && result.Duration > q.MaxLatency {
+		return false
+	}
+	if q.Status != "" {
+		low, high, ok := ParseStatus(q.Status)
+		if !ok || result.StatusCode < low || result.StatusCode > high {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataIndex 缓存已读取的元数据记录。分析文件保存后不再修改，
+// 因此记录可以一直缓存，直到对应文件从存储中消失
+type metadataIndex struct {
+	mu      sync.Mutex
+	records map[string]ProfilingResult
+}
+
+// newMetadataIndex 创建元数据索引
+func newMetadataIndex() *metadataIndex {
+	return &metadataIndex{records: make(map[string]ProfilingResult)}
+}
+
+// add 缓存刚保存的元数据记录
+func (i *metadataIndex) add(metadataFile string, result ProfilingResult) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.records[metadataFile] = result
+}
+
+// query 列出存储中的元数据记录并按条件过滤，结果按开始时间从新到旧排序。
+// 列出和读取存储时不持有锁，保存分析文件时的add不会等待存储的网络往返
+func (i *metadataIndex) query(ctx context.Context, storage Storage, q ProfileQuery) ([]ProfilingResult, error) {
+	reader, ok := storage.(StorageReader)
+	if !ok {
+		return nil, ErrStorageNotReadable
+	}
+
+	profileType := q.ProfileType
+	if profileType == "" {
+		profileType = "*"
+	}
+	files, err := storage.List(ctx, path.Join(profileType, "*"+MetadataExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	// 在锁内取出已缓存的记录，并找出已从存储中消失的记录；
+	// 按类型查询时保留其他类型的缓存
+	records, missing, stale := i.snapshot(files, q.ProfileType == "")
+
+	fetched := make(map[string]ProfilingResult, len(missing))
+	for _, file := range missing {
+		result, err := readMetadata(ctx, reader, file)
+		if err != nil {
+			continue
+		}
+		fetched[file] = result
+		records[file] = result
+	}
+	i.merge(fetched, stale)
+
+	var results []ProfilingResult
+	for _, result := range records {
+		if q.Matches(result) {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].StartTime.After(results[b].StartTime)
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+// snapshot 返回files中已缓存的记录和未缓存的文件；prune为true时
+// 同时返回缓存中不在files里的记录
+func (i *metadataIndex) snapshot(files []string, prune bool) (map[string]ProfilingResult, []string, []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	records := make(map[string]ProfilingResult, len(files))
+	var missing []string
+	for _, file := range files {
+		if result, cached := i.records[file]; cached {
+			records[file] = result
+		} else {
+			missing = append(missing, file)
+		}
+	}
+
+	var stale []string
+	if prune {
+		for file := range i.records {
+			if _, listed := records[file]; !listed {
+				stale = append(stale, file)
+			}
+		}
+	}
+	return records, missing, stale
+}
+
+// merge 缓存新读取的记录并删除已消失的记录
+func (i *metadataIndex) merge(fetched map[string]ProfilingResult, stale []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, file := range stale {
+		delete(i.records, file)
+	}
+	for file, result := range fetched {
+		i.records[file] = result
+	}
+}
+
+// readMetadata 读取并解析一条元数据记录
+func readMetadata(ctx context.Context, reader StorageReader, file string) (ProfilingResult, error) {
+	rc, err := reader.Open(ctx, file)
+	if err != nil {
+		return ProfilingResult{}, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return ProfilingResult{}, err
+	}
+
+	var result ProfilingResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return ProfilingResult{}, err
+	}
+	return result, nil
+}
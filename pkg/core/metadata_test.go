@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// blockingStorage blocks Open until release is closed
+type blockingStorage struct {
+	*readableStorage
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	s.opened <- struct{}{}
+	<-s.release
+	return s.readableStorage.Open(ctx, filename)
+}
+
+func saveRecord(t *testing.T, storage *readableStorage, result ProfilingResult) {
+	t.Helper()
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.put(MetadataFilename(result.Filename), data, int64(len(data)))
+}
+
+func TestMetadataIndexAddDoesNotWaitForQuery(t *testing.T) {
+	inner := &readableStorage{files: make(map[string]storedFile)}
+	storage := &blockingStorage{readableStorage: inner, opened: make(chan struct{}, 1), release: make(chan struct{})}
+	saveRecord(t, inner, ProfilingResult{ProfileID: "a", ProfileType: "cpu", Filename: "cpu/profile_a.pprof"})
+
+	index := newMetadataIndex()
+	done := make(chan []ProfilingResult)
+	go func() {
+		results, err := index.query(context.Background(), storage, ProfileQuery{})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- results
+	}()
+
+	// While the query reads an uncached record, saving a profile must not block
+	<-storage.opened
+	added := make(chan struct{})
+	go func() {
+		index.add("cpu/profile_b.pprof.json", ProfilingResult{ProfileID: "b", ProfileType: "cpu"})
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("add waited for the query to read the storage")
+	}
+
+	close(storage.release)
+	if results := <-done; len(results) != 1 || results[0].ProfileID != "a" {
+		t.Fatalf("query returned %+v", results)
+	}
+
+	// Both records are cached; the next query reads nothing from the storage
+	saveRecord(t, inner, ProfilingResult{ProfileID: "b", ProfileType: "cpu", Filename: "cpu/profile_b.pprof"})
+	results, err := index.query(context.Background(), storage, ProfileQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("query returned %d records, want 2", len(results))
+	}
+	select {
+	case <-storage.opened:
+		t.Fatal("cached record was read again")
+	default:
+	}
+
+	// Records deleted from the storage are dropped from the cache
+	inner.Delete(context.Background(), "cpu/profile_a.pprof.json")
+	if results, _ := index.query(context.Background(), storage, ProfileQuery{}); len(results) != 1 || results[0].ProfileID != "b" {
+		t.Fatalf("query after delete returned %+v", results)
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if _, cached := index.records["cpu/profile_a.pprof.json"]; cached {
+		t.Fatal("deleted record is still cached")
+	}
+}
+
+func TestMetadataQueryByProfileID(t *testing.T) {
+	storage := &readableStorage{files: make(map[string]storedFile)}
+	for _, id := range []string{"a", "b", "c"} {
+		saveRecord(t, storage, ProfilingResult{ProfileID: id, ProfileType: "cpu", Filename: "cpu/profile_" + id + ".pprof"})
+	}
+
+	results, err := newMetadataIndex().query(context.Background(), storage, ProfileQuery{ProfileID: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Filename != "cpu/profile_b.pprof" {
+		t.Fatalf("query by profile ID returned %+v", results)
+	}
+}
@@ -24,6 +24,17 @@ type Options struct {
 	
 	// DefaultSampleRate is the default sample rate for profiling
 	DefaultSampleRate int `yaml:"default_sample_rate" json:"default_sample_rate"`
+
+	// Trigger configures header- and query-triggered profiling of single requests
+	Trigger TriggerOptions `yaml:"trigger" json:"trigger"`
+
+	// Retention limits the stored profiles by count and size on every cleanup
+	Retention RetentionPolicy `yaml:"retention" json:"retention"`
+
+	// Profilers are registered after the built-in profilers, replacing those
+	// of the same type, and before the first config sync, so tasks with a
+	// custom profile type pass validation
+	Profilers []Profiler `yaml:"-" json:"-"`
 }
 
 // DefaultOptions returns default configuration options
@@ -36,5 +47,6 @@ func DefaultOptions() Options {
 		Enabled:           true,
 		ProfileDir:        "./profiles",
 		DefaultSampleRate: 1,
+		Trigger:           DefaultTriggerOptions(),
 	}
 }
\ No newline at end of file
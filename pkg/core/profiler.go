@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
 	"sync"
 	"time"
 )
@@ -27,11 +29,15 @@ func (c *CPUProfiler) GetProfileType() string {
 	return "cpu"
 }
 
-// CPUProfileSession represents a CPU profiling session
+// CPUProfileSession represents a CPU profiling session. Concurrent sessions
+// share one runtime CPU profile through the CPU broker; each session only
+// keeps the samples carrying the pprof labels of its context.
 type CPUProfileSession struct {
 	ctx       context.Context
 	task      ProfilingTask
-	buffer    *bytes.Buffer
+	labels    map[string]string
+	data      []byte
+	err       error
 	startTime time.Time
 	mu        sync.Mutex
 	running   bool
@@ -42,14 +48,14 @@ func NewCPUProfileSession(ctx context.Context, task ProfilingTask) (ProfileSessi
 	session := &CPUProfileSession{
 		ctx:       ctx,
 		task:      task,
-		buffer:    new(bytes.Buffer),
+		labels:    sessionLabels(ctx),
 		startTime: time.Now(),
 		running:   true,
 	}
 
-	if err := pprof.StartCPUProfile(session.buffer); err != nil {
+	if err := defaultCPUBroker.join(session); err != nil {
 		session.running = false
-		return nil, fmt.Errorf("failed to start CPU profiling: %w", err)
+		return nil, err
 	}
 
 	// Set up automatic stop after duration
@@ -76,13 +82,19 @@ func (s *CPUProfileSession) Stop() ([]byte, error) {
 	defer s.mu.Unlock()
 
 	if !s.running {
-		return s.buffer.Bytes(), nil
+		return s.data, s.err
 	}
 
-	pprof.StopCPUProfile()
 	s.running = false
 
-	return s.buffer.Bytes(), nil
+	segments, err := defaultCPUBroker.leave(s)
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+
+	s.data, s.err = buildCPUView(segments, s.labels, s.startTime, time.Now())
+	return s.data, s.err
 }
 
 // GetStartTime returns when the session started
@@ -97,6 +109,95 @@ func (s *CPUProfileSession) IsRunning() bool {
 	return s.running
 }
 
+// TraceProfiler implements execution tracing
+type TraceProfiler struct{}
+
+// NewTraceProfiler creates a new execution trace profiler
+func NewTraceProfiler() Profiler {
+	return &TraceProfiler{}
+}
+
+// StartProfiling starts execution tracing
+func (t *TraceProfiler) StartProfiling(ctx context.Context, task ProfilingTask) (ProfileSession, error) {
+	return NewTraceProfileSession(ctx, task)
+}
+
+// GetProfileType returns the profiling type
+func (t *TraceProfiler) GetProfileType() string {
+	return "trace"
+}
+
+// TraceProfileSession represents an execution trace session.
+// Like CPU profiling, only one trace can be active per process.
+type TraceProfileSession struct {
+	ctx       context.Context
+	task      ProfilingTask
+	buffer    *bytes.Buffer
+	startTime time.Time
+	mu        sync.Mutex
+	running   bool
+}
+
+// NewTraceProfileSession creates a new execution trace session
+func NewTraceProfileSession(ctx context.Context, task ProfilingTask) (ProfileSession, error) {
+	session := &TraceProfileSession{
+		ctx:       ctx,
+		task:      task,
+		buffer:    new(bytes.Buffer),
+		startTime: time.Now(),
+		running:   true,
+	}
+
+	if err := trace.Start(session.buffer); err != nil {
+		session.running = false
+		return nil, fmt.Errorf("failed to start execution trace: %w", err)
+	}
+
+	// Set up automatic stop after duration
+	if task.Duration > 0 {
+		go func() {
+			timer := time.NewTimer(time.Duration(task.Duration) * time.Second)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+				session.Stop()
+			case <-ctx.Done():
+				session.Stop()
+			}
+		}()
+	}
+
+	return session, nil
+}
+
+// Stop stops the execution trace
+func (s *TraceProfileSession) Stop() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return s.buffer.Bytes(), nil
+	}
+
+	trace.Stop()
+	s.running = false
+
+	return s.buffer.Bytes(), nil
+}
+
+// GetStartTime returns when the session started
+func (s *TraceProfileSession) GetStartTime() time.Time {
+	return s.startTime
+}
+
+// IsRunning returns true if the session is still active
+func (s *TraceProfileSession) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
 // HeapProfiler implements heap/memory profiling
 type HeapProfiler struct{}
 
@@ -119,6 +220,8 @@ func (h *HeapProfiler) GetProfileType() string {
 type HeapProfileSession struct {
 	ctx       context.Context
 	task      ProfilingTask
+	data      []byte
+	err       error
 	startTime time.Time
 	mu        sync.Mutex
 	running   bool
@@ -158,8 +261,9 @@ func (s *HeapProfileSession) Stop() ([]byte, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A session stopped by its deadline returns the captured data again
 	if s.stopped {
-		return nil, nil
+		return s.data, s.err
 	}
 
 	s.running = false
@@ -168,10 +272,12 @@ func (s *HeapProfileSession) Stop() ([]byte, error) {
 	// Capture heap profile
 	buffer := new(bytes.Buffer)
 	if err := pprof.WriteHeapProfile(buffer); err != nil {
-		return nil, fmt.Errorf("failed to write heap profile: %w", err)
+		s.err = fmt.Errorf("failed to write heap profile: %w", err)
+		return nil, s.err
 	}
 
-	return buffer.Bytes(), nil
+	s.data = buffer.Bytes()
+	return s.data, nil
 }
 
 // GetStartTime returns when the session started
@@ -208,6 +314,8 @@ func (g *GoroutineProfiler) GetProfileType() string {
 type GoroutineProfileSession struct {
 	ctx       context.Context
 	task      ProfilingTask
+	data      []byte
+	err       error
 	startTime time.Time
 	mu        sync.Mutex
 	running   bool
@@ -247,8 +355,9 @@ func (s *GoroutineProfileSession) Stop() ([]byte, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A session stopped by its deadline returns the captured data again
 	if s.stopped {
-		return nil, nil
+		return s.data, s.err
 	}
 
 	s.running = false
@@ -257,15 +366,18 @@ func (s *GoroutineProfileSession) Stop() ([]byte, error) {
 	// Capture goroutine profile
 	profile := pprof.Lookup("goroutine")
 	if profile == nil {
-		return nil, fmt.Errorf("goroutine profile not found")
+		s.err = fmt.Errorf("goroutine profile not found")
+		return nil, s.err
 	}
 
 	buffer := new(bytes.Buffer)
 	if err := profile.WriteTo(buffer, 0); err != nil {
-		return nil, fmt.Errorf("failed to write goroutine profile: %w", err)
+		s.err = fmt.Errorf("failed to write goroutine profile: %w", err)
+		return nil, s.err
 	}
 
-	return buffer.Bytes(), nil
+	s.data = buffer.Bytes()
+	return s.data, nil
 }
 
 // GetStartTime returns when the session started
@@ -278,4 +390,259 @@ func (s *GoroutineProfileSession) IsRunning() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.running
-}
\ No newline at end of file
+}
+
+// Sampling rates applied while a contention profiling session is active
+const (
+	// DefaultMutexProfileFraction reports every mutex contention event
+	DefaultMutexProfileFraction = 1
+	// DefaultBlockProfileRate records every blocking event
+	DefaultBlockProfileRate = 1
+)
+
+// unknownRate is returned by contentionRate.set when the runtime cannot
+// report the previous rate
+const unknownRate = -1
+
+// contentionRate reference-counts a runtime sampling rate so overlapping
+// sessions share it and the last session to stop restores the previous rate
+type contentionRate struct {
+	mu       sync.Mutex
+	refs     int
+	previous int
+	set      func(rate int) int
+}
+
+var (
+	mutexRate = &contentionRate{set: runtime.SetMutexProfileFraction}
+	blockRate = &contentionRate{set: func(rate int) int {
+		// The runtime has no getter for the block profile rate, so the rate
+		// to restore is taken from BlockProfilerOptions.RestoreRate
+		runtime.SetBlockProfileRate(rate)
+		return unknownRate
+	}}
+)
+
+// acquire enables the sampling rate for a new session. restore is the rate
+// put back after the last session when the runtime cannot report it.
+func (r *contentionRate) acquire(rate, restore int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.refs == 0 {
+		r.previous = r.set(rate)
+		if r.previous == unknownRate {
+			r.previous = restore
+		}
+	}
+	r.refs++
+}
+
+// release restores the previous rate once no session needs it anymore
+func (r *contentionRate) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refs--
+	if r.refs == 0 {
+		r.set(r.previous)
+	}
+}
+
+// MutexProfiler implements mutex contention profiling
+type MutexProfiler struct {
+	fraction int
+}
+
+// NewMutexProfiler creates a new mutex profiler
+func NewMutexProfiler() Profiler {
+	return &MutexProfiler{fraction: DefaultMutexProfileFraction}
+}
+
+// StartProfiling starts mutex profiling
+func (m *MutexProfiler) StartProfiling(ctx context.Context, task ProfilingTask) (ProfileSession, error) {
+	return newDeltaProfileSession(ctx, task, "mutex", mutexRate, m.fraction, 0)
+}
+
+// GetProfileType returns the profiling type
+func (m *MutexProfiler) GetProfileType() string {
+	return "mutex"
+}
+
+// BlockProfilerOptions configures a BlockProfiler
+type BlockProfilerOptions struct {
+	// Rate is the block profile rate applied while a session is active,
+	// DefaultBlockProfileRate when 0
+	Rate int
+	// RestoreRate is the block profile rate set when the last session stops.
+	// The runtime cannot report the current rate, so applications that call
+	// runtime.SetBlockProfileRate themselves must declare their rate here;
+	// otherwise block profiling is switched off (rate 0) after a session.
+	RestoreRate int
+}
+
+// BlockProfiler implements blocking profiling
+type BlockProfiler struct {
+	rate        int
+	restoreRate int
+}
+
+// NewBlockProfiler creates a new block profiler
+func NewBlockProfiler() Profiler {
+	return NewBlockProfilerWithOptions(BlockProfilerOptions{})
+}
+
+// NewBlockProfilerWithOptions creates a block profiler with custom rates.
+// Register it with Manager.RegisterProfiler to replace the default one.
+func NewBlockProfilerWithOptions(opts BlockProfilerOptions) Profiler {
+	rate := opts.Rate
+	if rate <= 0 {
+		rate = DefaultBlockProfileRate
+	}
+	return &BlockProfiler{rate: rate, restoreRate: opts.RestoreRate}
+}
+
+// StartProfiling starts block profiling
+func (b *BlockProfiler) StartProfiling(ctx context.Context, task ProfilingTask) (ProfileSession, error) {
+	return newDeltaProfileSession(ctx, task, "block", blockRate, b.rate, b.restoreRate)
+}
+
+// GetProfileType returns the profiling type
+func (b *BlockProfiler) GetProfileType() string {
+	return "block"
+}
+
+// HeapDeltaProfiler implements heap profiling limited to the session window
+type HeapDeltaProfiler struct {
+	profileName string
+	profileType string
+}
+
+// NewHeapDeltaProfiler creates a profiler that reports the heap profile
+// difference between session start and stop
+func NewHeapDeltaProfiler() Profiler {
+	return &HeapDeltaProfiler{profileName: "heap", profileType: "heap_delta"}
+}
+
+// NewAllocsDeltaProfiler creates a profiler that reports the allocations
+// made between session start and stop
+func NewAllocsDeltaProfiler() Profiler {
+	return &HeapDeltaProfiler{profileName: "allocs", profileType: "allocs_delta"}
+}
+
+// StartProfiling starts heap delta profiling
+func (h *HeapDeltaProfiler) StartProfiling(ctx context.Context, task ProfilingTask) (ProfileSession, error) {
+	return newDeltaProfileSession(ctx, task, h.profileName, nil, 0, 0)
+}
+
+// GetProfileType returns the profiling type
+func (h *HeapDeltaProfiler) GetProfileType() string {
+	return h.profileType
+}
+
+// DeltaProfileSession represents a session over a cumulative runtime profile
+// (mutex, block, heap or allocs). The profile is snapshotted at start and at
+// Stop, and only the samples recorded in between are returned.
+type DeltaProfileSession struct {
+	ctx         context.Context
+	task        ProfilingTask
+	profileName string
+	rate        *contentionRate
+	base        []byte
+	data        []byte
+	err         error
+	startTime   time.Time
+	mu          sync.Mutex
+	running     bool
+}
+
+// newDeltaProfileSession creates a new delta profiling session. When rate is
+// not nil the sampling rate is enabled for the lifetime of the session and
+// restoreRate is put back afterwards if the runtime cannot report the previous rate.
+func newDeltaProfileSession(ctx context.Context, task ProfilingTask, profileName string, rate *contentionRate, sampleRate, restoreRate int) (ProfileSession, error) {
+	// Enable sampling before the snapshot so both snapshots use the same scale
+	if rate != nil {
+		rate.acquire(sampleRate, restoreRate)
+	}
+
+	base, err := snapshotDeltaBase(profileName)
+	if err != nil {
+		if rate != nil {
+			rate.release()
+		}
+		return nil, err
+	}
+
+	session := &DeltaProfileSession{
+		ctx:         ctx,
+		task:        task,
+		profileName: profileName,
+		rate:        rate,
+		base:        base,
+		startTime:   time.Now(),
+		running:     true,
+	}
+
+	// Set up automatic stop after duration
+	if task.Duration > 0 {
+		go func() {
+			timer := time.NewTimer(time.Duration(task.Duration) * time.Second)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+				session.Stop()
+			case <-ctx.Done():
+				session.Stop()
+			}
+		}()
+	}
+
+	return session, nil
+}
+
+// Stop stops the profiling session and returns the profile delta
+func (s *DeltaProfileSession) Stop() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return s.data, s.err
+	}
+
+	s.running = false
+	if s.rate != nil {
+		defer s.rate.release()
+	}
+
+	current, err := snapshotDeltaBase(s.profileName)
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+
+	s.data, s.err = diffProfiles(s.base, current)
+	return s.data, s.err
+}
+
+// GetStartTime returns when the session started
+func (s *DeltaProfileSession) GetStartTime() time.Time {
+	return s.startTime
+}
+
+// IsRunning returns true if the session is still active
+func (s *DeltaProfileSession) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// snapshotDeltaBase captures a snapshot of the named profile. Heap profiles
+// only reflect the state as of the last completed GC, so a collection is
+// forced first to include the most recent allocations.
+func snapshotDeltaBase(profileName string) ([]byte, error) {
+	if profileName == "heap" || profileName == "allocs" {
+		runtime.GC()
+	}
+	return snapshotProfile(profileName)
+}
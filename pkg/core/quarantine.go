@@ -0,0 +1,94 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QuarantinedTask 表示因配置无效而被拒绝的任务
+type QuarantinedTask struct {
+	Task         ProfilingTask `json:"task"`          // 被拒绝的任务配置
+	Errors       []FieldError  `json:"errors"`        // 字段问题列表
+	RejectedAt   time.Time     `json:"rejected_at"`   // 拒绝时间
+	KeptPrevious bool          `json:"kept_previous"` // 是否仍使用该任务上一次有效的配置
+}
+
+// TaskQuarantine 由能够报告被拒绝任务的配置提供器实现
+type TaskQuarantine interface {
+	// QuarantinedTasks 返回最近一次加载中被拒绝的任务
+	QuarantinedTasks() []QuarantinedTask
+}
+
+// TaskValidator 在配置加载时校验任务，将无效任务隔离。
+// 已有ID的任务在新配置无效时继续使用其上一次有效的配置
+type TaskValidator struct {
+	mu          sync.Mutex
+	lastValid   map[string]ProfilingTask
+	quarantined []QuarantinedTask
+}
+
+// NewTaskValidator 创建任务校验器
+func NewTaskValidator() *TaskValidator {
+	return &TaskValidator{lastValid: make(map[string]ProfilingTask)}
+}
+
+// Filter 返回可以生效的任务，并记录本次被隔离的任务。配置了ID的任务以ID识别；
+// 未配置ID的任务以路径及其在同一路径任务中的顺序识别，因此分析类型或方法写错时
+// 仍能找到上一次有效的配置。不再出现在配置中的任务会被遗忘
+func (v *TaskValidator) Filter(tasks []ProfilingTask, now time.Time) ([]ProfilingTask, []QuarantinedTask) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	lastValid := make(map[string]ProfilingTask, len(tasks))
+	occurrences := make(map[string]int)
+	var valid []ProfilingTask
+	var quarantined []QuarantinedTask
+	for _, task := range tasks {
+		key := "id:" + task.ID
+		if task.ID == "" {
+			key = fmt.Sprintf("path:%s#%d", task.Path, occurrences[task.Path])
+			occurrences[task.Path]++
+		}
+
+		err := task.Validate()
+		if err == nil {
+			lastValid[key] = task
+			valid = append(valid, task)
+			continue
+		}
+
+		entry := newQuarantinedTask(task, err, now)
+		if previous, exists := v.lastValid[key]; exists {
+			lastValid[key] = previous
+			valid = append(valid, previous)
+			entry.KeptPrevious = true
+		}
+		quarantined = append(quarantined, entry)
+	}
+
+	v.lastValid = lastValid
+	v.quarantined = quarantined
+	return valid, quarantined
+}
+
+// QuarantinedTasks 返回最近一次Filter中被隔离的任务
+func (v *TaskValidator) QuarantinedTasks() []QuarantinedTask {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return append([]QuarantinedTask(nil), v.quarantined...)
+}
+
+// newQuarantinedTask 根据校验错误创建隔离记录
+func newQuarantinedTask(task ProfilingTask, err error, now time.Time) QuarantinedTask {
+	entry := QuarantinedTask{Task: task, RejectedAt: now}
+	var validationErr *TaskValidationError
+	if errors.As(err, &validationErr) {
+		entry.Errors = validationErr.Errors
+	} else {
+		entry.Errors = []FieldError{{Message: err.Error()}}
+	}
+	return entry
+}
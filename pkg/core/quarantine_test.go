@@ -0,0 +1,117 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func validTask(path, profileType string) ProfilingTask {
+	return ProfilingTask{
+		Path:        path,
+		Methods:     []string{"GET"},
+		ProfileType: profileType,
+		Duration:    10,
+		SampleRate:  1,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+}
+
+func TestTaskValidatorKeepsPreviousVersion(t *testing.T) {
+	withID := func(task ProfilingTask, id string) ProfilingTask {
+		task.ID = id
+		return task
+	}
+	withMethods := func(task ProfilingTask, methods ...string) ProfilingTask {
+		task.Methods = methods
+		return task
+	}
+
+	tests := []struct {
+		name         string
+		before       []ProfilingTask
+		after        []ProfilingTask
+		wantValid    []string // profile types of the active tasks
+		keptPrevious bool
+	}{
+		{
+			name:         "profile type typo without id",
+			before:       []ProfilingTask{validTask("/a", "cpu")},
+			after:        []ProfilingTask{validTask("/a", "cpuu")},
+			wantValid:    []string{"cpu"},
+			keptPrevious: true,
+		},
+		{
+			name:         "method typo without id",
+			before:       []ProfilingTask{validTask("/a", "heap")},
+			after:        []ProfilingTask{withMethods(validTask("/a", "heap"), "GTE")},
+			wantValid:    []string{"heap"},
+			keptPrevious: true,
+		},
+		{
+			name:         "second task on the same path",
+			before:       []ProfilingTask{validTask("/a", "cpu"), validTask("/a", "heap")},
+			after:        []ProfilingTask{validTask("/a", "cpu"), validTask("/a", "heapp")},
+			wantValid:    []string{"cpu", "heap"},
+			keptPrevious: true,
+		},
+		{
+			name:         "path error with id",
+			before:       []ProfilingTask{withID(validTask("/a", "cpu"), "a")},
+			after:        []ProfilingTask{withID(validTask("a", "cpu"), "a")},
+			wantValid:    []string{"cpu"},
+			keptPrevious: true,
+		},
+		{
+			name:         "new invalid task",
+			before:       []ProfilingTask{validTask("/a", "cpu")},
+			after:        []ProfilingTask{validTask("/a", "cpu"), validTask("/b", "cpuu")},
+			wantValid:    []string{"cpu"},
+			keptPrevious: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewTaskValidator()
+			if _, quarantined := v.Filter(tt.before, time.Now()); len(quarantined) != 0 {
+				t.Fatalf("initial tasks quarantined: %v", quarantined)
+			}
+
+			valid, quarantined := v.Filter(tt.after, time.Now())
+			var got []string
+			for _, task := range valid {
+				got = append(got, task.ProfileType)
+			}
+			if len(got) != len(tt.wantValid) {
+				t.Fatalf("active tasks = %v, want %v", got, tt.wantValid)
+			}
+			for i := range got {
+				if got[i] != tt.wantValid[i] {
+					t.Fatalf("active tasks = %v, want %v", got, tt.wantValid)
+				}
+			}
+
+			if len(quarantined) != 1 {
+				t.Fatalf("quarantined %d tasks, want 1", len(quarantined))
+			}
+			if quarantined[0].KeptPrevious != tt.keptPrevious {
+				t.Fatalf("KeptPrevious = %v, want %v", quarantined[0].KeptPrevious, tt.keptPrevious)
+			}
+			if len(quarantined[0].Errors) == 0 {
+				t.Fatal("quarantined task has no field errors")
+			}
+		})
+	}
+}
+
+func TestTaskValidatorForgetsRemovedTasks(t *testing.T) {
+	v := NewTaskValidator()
+	v.Filter([]ProfilingTask{validTask("/a", "cpu")}, time.Now())
+	v.Filter(nil, time.Now())
+
+	// A task that comes back invalid has no previous version anymore
+	valid, quarantined := v.Filter([]ProfilingTask{validTask("/a", "cpuu")}, time.Now())
+	if len(valid) != 0 || len(quarantined) != 1 || quarantined[0].KeptPrevious {
+		t.Fatalf("valid = %v, quarantined = %v", valid, quarantined)
+	}
+}
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 淘汰原因，记录在清理日志中
+const (
+	retentionReasonTask       = "keep_latest_per_task"
+	retentionReasonRoute      = "max_files_per_route"
+	retentionReasonType       = "max_files_per_type"
+	retentionReasonTotalBytes = "max_total_bytes"
+)
+
+// RetentionPolicy 定义清理例程在MaxFileAge之外应用的保留策略，
+// 超出限制时先删除最旧的分析文件。零值字段表示不限制
+type RetentionPolicy struct {
+	// MaxTotalBytes 所有分析文件及其元数据记录在存储中占用的总字节数上限，
+	// 压缩存储按压缩后的大小计算
+	MaxTotalBytes int64 `yaml:"max_total_bytes" json:"max_total_bytes"`
+
+	// MaxFilesPerType 每种分析类型保留的文件数上限
+	MaxFilesPerType int `yaml:"max_files_per_type" json:"max_files_per_type"`
+
+	// MaxFilesPerRoute 每个路由模板保留的文件数上限
+	MaxFilesPerRoute int `yaml:"max_files_per_route" json:"max_files_per_route"`
+
+	// KeepLatestPerTask 每个任务只保留最新的N个文件
+	KeepLatestPerTask int `yaml:"keep_latest_per_task" json:"keep_latest_per_task"`
+}
+
+// IsZero 检查策略是否未设置任何限制
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxTotalBytes <= 0 && p.MaxFilesPerType <= 0 && p.MaxFilesPerRoute <= 0 && p.KeepLatestPerTask <= 0
+}
+
+// retainedFile 是保留策略评估的一个分析文件
+type retainedFile struct {
+	Filename    string
+	ProfileType string
+	Route       string
+	TaskID      string
+	Size        int64 // 分析文件和元数据记录在存储中占用的字节数
+	CreatedAt   time.Time
+	HasMetadata bool
+}
+
+// eviction 是保留策略选出的待删除文件及原因
+type eviction struct {
+	file   retainedFile
+	reason string
+}
+
+// evict 按策略选出要删除的文件。依次应用每任务、每路由、每类型的数量限制，
+// 最后应用总大小限制；每一步都从最旧的文件开始删除
+func (p RetentionPolicy) evict(files []retainedFile) []eviction {
+	// 按创建时间从新到旧排序，同一时间按文件名保证顺序稳定
+	sorted := append([]retainedFile(nil), files...)
+	sort.Slice(sorted, func(a, b int) bool {
+		if !sorted[a].CreatedAt.Equal(sorted[b].CreatedAt) {
+			return sorted[a].CreatedAt.After(sorted[b].CreatedAt)
+		}
+		return sorted[a].Filename > sorted[b].Filename
+	})
+
+	var evicted []eviction
+	kept := sorted
+
+	// limitPerKey 为每个键保留最新的limit个文件
+	limitPerKey := func(limit int, reason string, key func(retainedFile) string) {
+		if limit <= 0 {
+			return
+		}
+		counts := make(map[string]int)
+		var remaining []retainedFile
+		var dropped []eviction
+		for _, file := range kept {
+			k := key(file)
+			if k == "" {
+				remaining = append(remaining, file)
+				continue
+			}
+			counts[k]++
+			if counts[k] > limit {
+				dropped = append(dropped, eviction{file: file, reason: reason})
+				continue
+			}
+			remaining = append(remaining, file)
+		}
+		// dropped按从新到旧排列，反转后先记录最旧的文件
+		for i := len(dropped) - 1; i >= 0; i-- {
+			evicted = append(evicted, dropped[i])
+		}
+		kept = remaining
+	}
+
+	limitPerKey(p.KeepLatestPerTask, retentionReasonTask, func(f retainedFile) string { return f.TaskID })
+	limitPerKey(p.MaxFilesPerRoute, retentionReasonRoute, func(f retainedFile) string { return f.Route })
+	limitPerKey(p.MaxFilesPerType, retentionReasonType, func(f retainedFile) string { return f.ProfileType })
+
+	if p.MaxTotalBytes > 0 {
+		var total int64
+		for _, file := range kept {
+			total += file.Size
+		}
+		for i := len(kept) - 1; i >= 0 && total > p.MaxTotalBytes; i-- {
+			evicted = append(evicted, eviction{file: kept[i], reason: retentionReasonTotalBytes})
+			total -= kept[i].Size
+		}
+	}
+
+	return evicted
+}
+
+// applyRetention 对存储中的分析文件应用保留策略，删除被淘汰的文件及其元数据记录。
+// 路由和任务来自元数据记录，大小为存储中实际占用的字节数
+func (m *Manager) applyRetention(ctx context.Context) {
+	policy := m.options.Retention
+	if policy.IsZero() {
+		return
+	}
+
+	reader, ok := m.storage.(StorageReader)
+	if !ok {
+		m.logger.Warn("Retention policy skipped, storage is not readable", nil)
+		return
+	}
+
+	files, err := m.retainedFiles(ctx, reader)
+	if err != nil {
+		m.logger.Error("Failed to list profiles for retention", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var evictedCount int
+	var freedBytes int64
+	for _, e := range policy.evict(files) {
+		if err := m.storage.Delete(ctx, e.file.Filename); err != nil {
+			m.logger.Error("Failed to evict profile", map[string]interface{}{
+				"filename": e.file.Filename,
+				"reason":   e.reason,
+				"error":    err.Error(),
+			})
+			continue
+		}
+		if e.file.HasMetadata {
+			if err := m.storage.Delete(ctx, MetadataFilename(e.file.Filename)); err != nil {
+				m.logger.Warn("Failed to delete metadata of evicted profile", map[string]interface{}{
+					"filename": e.file.Filename,
+					"error":    err.Error(),
+				})
+			}
+		}
+
+		evictedCount++
+		freedBytes += e.file.Size
+		m.logger.Info("Profile evicted by retention policy", map[string]interface{}{
+			"filename":   e.file.Filename,
+			"reason":     e.reason,
+			"size":       e.file.Size,
+			"type":       e.file.ProfileType,
+			"route":      e.file.Route,
+			"task_id":    e.file.TaskID,
+			"created_at": e.file.CreatedAt,
+		})
+	}
+
+	if evictedCount > 0 {
+		m.logger.Info("Retention policy applied", map[string]interface{}{
+			"evicted":     evictedCount,
+			"freed_bytes": freedBytes,
+			"remaining":   len(files) - evictedCount,
+		})
+	}
+}
+
+// retainedFiles 列出存储中的分析文件，每个文件的大小包含其元数据记录
+func (m *Manager) retainedFiles(ctx context.Context, reader StorageReader) ([]retainedFile, error) {
+	names, err := m.storage.List(ctx, "*/*")
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.metadata.query(ctx, m.storage, ProfileQuery{})
+	if err != nil {
+		return nil, err
+	}
+	byFilename := make(map[string]ProfilingResult, len(records))
+	for _, record := range records {
+		byFilename[record.Filename] = record
+	}
+
+	var files []retainedFile
+	for _, name := range names {
+		if strings.HasSuffix(name, MetadataExtension) {
+			continue
+		}
+
+		record, hasMetadata := byFilename[name]
+		file := retainedFile{
+			Filename:    name,
+			ProfileType: record.ProfileType,
+			Route:       record.Path,
+			TaskID:      record.TaskID,
+			Size:        record.StoredSize,
+			CreatedAt:   record.StartTime,
+			HasMetadata: hasMetadata,
+		}
+
+		// 没有记录或记录早于StoredSize字段的文件需要查询存储
+		if file.Size == 0 {
+			info, err := reader.Stat(ctx, name)
+			if err != nil {
+				// 文件可能已被并发删除
+				continue
+			}
+			file.Size = storedSize(info)
+			if !hasMetadata {
+				file.ProfileType = FileMetadata(name)[MetadataProfileType]
+				file.CreatedAt = info.ModTime
+			}
+		}
+
+		if hasMetadata {
+			if info, err := reader.Stat(ctx, MetadataFilename(name)); err == nil {
+				file.Size += storedSize(info)
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// storedSize 返回文件在存储中占用的字节数
+func storedSize(info FileInfo) int64 {
+	if info.StoredSize > 0 {
+		return info.StoredSize
+	}
+	return info.Size
+}
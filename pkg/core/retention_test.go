@@ -0,0 +1,147 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// storedFile is a file of readableStorage
+type storedFile struct {
+	data       []byte
+	storedSize int64
+}
+
+// readableStorage keeps files in memory and reports a stored size that
+// differs from the data length, like a compressing storage
+type readableStorage struct {
+	mu    sync.Mutex
+	files map[string]storedFile
+}
+
+func (s *readableStorage) put(name string, data []byte, storedSize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = storedFile{data: data, storedSize: storedSize}
+}
+
+func (s *readableStorage) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *readableStorage) Save(ctx context.Context, filename string, data []byte) error {
+	s.put(filename, data, int64(len(data)))
+	return nil
+}
+
+func (s *readableStorage) List(ctx context.Context, pattern string) ([]string, error) {
+	var matches []string
+	for _, name := range s.names() {
+		if matched, _ := path.Match(pattern, name); matched {
+			matches = append(matches, name)
+		}
+	}
+	return matches, nil
+}
+
+func (s *readableStorage) Delete(ctx context.Context, filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, filename)
+	return nil
+}
+
+func (s *readableStorage) Clean(ctx context.Context, maxAge time.Duration) error { return nil }
+
+func (s *readableStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, exists := s.files[filename]
+	if !exists {
+		return nil, fs.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(file.data)), nil
+}
+
+func (s *readableStorage) Stat(ctx context.Context, filename string) (FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, exists := s.files[filename]
+	if !exists {
+		return FileInfo{}, fs.ErrNotExist
+	}
+	return FileInfo{Name: filename, Size: int64(len(file.data)), StoredSize: file.storedSize, ModTime: time.Now()}, nil
+}
+
+func TestRetentionCountsStoredBytes(t *testing.T) {
+	storage := &readableStorage{files: make(map[string]storedFile)}
+	start := time.Now().Add(-time.Hour)
+
+	// Three compressed traces of 1000 bytes stored in 100 bytes, each with a
+	// metadata record stored in 20 bytes
+	for i, id := range []string{"a", "b", "c"} {
+		filename := "trace/profile_" + id + ".trace"
+		result := ProfilingResult{
+			TaskID:      "trace",
+			Path:        "/api/orders",
+			ProfileType: "trace",
+			Filename:    filename,
+			FileSize:    1000,
+			StoredSize:  100,
+			StartTime:   start.Add(time.Duration(i) * time.Minute),
+		}
+		record, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		storage.put(filename, make([]byte, 1000), 100)
+		storage.put(MetadataFilename(filename), record, 20)
+	}
+	// A profile without a record counts its stored size
+	storage.put("cpu/profile_d.pprof", make([]byte, 50), 0)
+
+	opts := DefaultOptions()
+	opts.Retention = RetentionPolicy{MaxTotalBytes: 300}
+	provider := &staticProvider{tasks: []ProfilingTask{validTask("/api/orders", "cpu")}}
+	m := newTestManager(t, provider, storage, opts)
+
+	files, err := m.retainedFiles(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	if len(files) != 4 || total != 3*120+50 {
+		t.Fatalf("retained %d files of %d bytes, want 4 files of %d bytes", len(files), total, 3*120+50)
+	}
+
+	// 410 bytes are stored; evicting the oldest trace and its record frees
+	// 120 bytes, which is enough. The uncompressed sizes would evict all
+	// traces.
+	m.applyRetention(context.Background())
+	want := []string{
+		"cpu/profile_d.pprof",
+		"trace/profile_b.trace", "trace/profile_b.trace.json",
+		"trace/profile_c.trace", "trace/profile_c.trace.json",
+	}
+	if got := storage.names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files after retention = %v, want %v", got, want)
+	}
+}
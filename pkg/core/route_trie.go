@@ -0,0 +1,188 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// Segment kinds ordered by specificity: a literal segment beats a parameter,
+// a parameter beats a single-segment wildcard, and a wildcard beats a
+// catch-all ("*name" or "**")
+const (
+	segmentCatchAll = iota
+	segmentWildcard
+	segmentParam
+	segmentStatic
+)
+
+// routeTrie 将任务路径预编译为按路径段索引的前缀树，
+// 匹配开销与请求路径长度相关，而不是与任务数量相关。
+// 前缀树只负责筛选候选任务，最终结果仍由PathMatcher确认；
+// 正则路径无法按段索引，放在fallback中逐个匹配
+type routeTrie struct {
+	root     *routeNode
+	fallback []routeEntry
+}
+
+// routeNode 表示前缀树中的一个路径段
+type routeNode struct {
+	static   map[string]*routeNode
+	param    *routeNode
+	wildcard *routeNode
+	catchAll *routeNode
+	entries  []routeEntry
+}
+
+// routeEntry 表示挂在前缀树节点上的任务
+type routeEntry struct {
+	task        ProfilingTask
+	specificity []int
+}
+
+// newRouteTrie 根据任务列表构建前缀树
+func newRouteTrie(tasks map[string]ProfilingTask) *routeTrie {
+	t := &routeTrie{root: newRouteNode()}
+	for _, task := range tasks {
+		t.insert(task)
+	}
+	return t
+}
+
+// newRouteNode 创建前缀树节点
+func newRouteNode() *routeNode {
+	return &routeNode{static: make(map[string]*routeNode)}
+}
+
+// insert 将任务插入前缀树
+func (t *routeTrie) insert(task ProfilingTask) {
+	if strings.HasPrefix(task.Path, RegexPathPrefix) {
+		t.fallback = append(t.fallback, routeEntry{task: task})
+		return
+	}
+
+	node := t.root
+	segments := splitPath(task.Path)
+	specificity := make([]int, len(segments))
+
+	for i, segment := range segments {
+		kind := segmentKind(segment)
+		specificity[i] = kind
+
+		switch kind {
+		case segmentParam:
+			if node.param == nil {
+				node.param = newRouteNode()
+			}
+			node = node.param
+		case segmentWildcard:
+			if node.wildcard == nil {
+				node.wildcard = newRouteNode()
+			}
+			node = node.wildcard
+		case segmentCatchAll:
+			if node.catchAll == nil {
+				node.catchAll = newRouteNode()
+			}
+			node = node.catchAll
+		default:
+			child, exists := node.static[segment]
+			if !exists {
+				child = newRouteNode()
+				node.static[segment] = child
+			}
+			node = child
+		}
+	}
+
+	node.entries = append(node.entries, routeEntry{task: task, specificity: specificity})
+}
+
+// match 返回路径命中的所有候选任务，按优先级、路由具体程度和任务ID排序
+func (t *routeTrie) match(path string) []routeEntry {
+	var collected []routeEntry
+	t.root.collect(splitPath(path), &collected)
+	collected = append(collected, t.fallback...)
+
+	// "**" 可以以多种方式展开，同一任务可能被收集多次
+	matched := make([]routeEntry, 0, len(collected))
+	seen := make(map[string]bool, len(collected))
+	for _, entry := range collected {
+		if seen[entry.task.ID] {
+			continue
+		}
+		seen[entry.task.ID] = true
+		matched = append(matched, entry)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].before(matched[j])
+	})
+	return matched
+}
+
+// collect 深度优先收集命中剩余路径段的任务
+func (n *routeNode) collect(segments []string, matched *[]routeEntry) {
+	if n.catchAll != nil {
+		// 通配段可以匹配零个或多个剩余路径段
+		for i := 0; i <= len(segments); i++ {
+			n.catchAll.collect(segments[i:], matched)
+		}
+	}
+
+	if len(segments) == 0 {
+		*matched = append(*matched, n.entries...)
+		return
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if child, exists := n.static[segment]; exists {
+		child.collect(rest, matched)
+	}
+	if n.param != nil {
+		n.param.collect(rest, matched)
+	}
+	if n.wildcard != nil {
+		n.wildcard.collect(rest, matched)
+	}
+}
+
+// before 判断任务a是否应优先于任务b：优先级高者优先，
+// 其次逐段比较路由具体程度，最后按任务ID保证顺序确定
+func (a routeEntry) before(b routeEntry) bool {
+	if a.task.Priority != b.task.Priority {
+		return a.task.Priority > b.task.Priority
+	}
+
+	for i := 0; i < len(a.specificity) && i < len(b.specificity); i++ {
+		if a.specificity[i] != b.specificity[i] {
+			return a.specificity[i] > b.specificity[i]
+		}
+	}
+	if len(a.specificity) != len(b.specificity) {
+		return len(a.specificity) > len(b.specificity)
+	}
+
+	return a.task.ID < b.task.ID
+}
+
+// segmentKind 返回路径段的类型。gin的 "*name" 与glob的 "**" 都按剩余路径索引，
+// 其余含有glob元字符的路径段（如 "report-*"）按单段通配索引
+func segmentKind(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, ":"):
+		return segmentParam
+	case segment == "*":
+		return segmentWildcard
+	case strings.HasPrefix(segment, "*"):
+		return segmentCatchAll
+	case strings.ContainsAny(segment, "*?["):
+		return segmentWildcard
+	default:
+		return segmentStatic
+	}
+}
+
+// splitPath 将路径拆分为路径段
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouteTrieMatchOrder(t *testing.T) {
+	tasks := map[string]ProfilingTask{}
+	for _, task := range []ProfilingTask{
+		{ID: "static", Path: "/api/users/me"},
+		{ID: "param", Path: "/api/users/:id"},
+		{ID: "priority", Path: "/api/users/:id", Priority: 10},
+		{ID: "wildcard", Path: "/api/users/*"},
+		{ID: "glob-segment", Path: "/api/users/m?"},
+		{ID: "catch-all", Path: "/api/*rest"},
+		{ID: "globstar", Path: "/api/**"},
+		{ID: "globstar-suffix", Path: "/api/**/posts"},
+		{ID: "posts", Path: "/api/users/:id/posts"},
+		{ID: "other", Path: "/other"},
+		{ID: "regex", Path: RegexPathPrefix + "^/api/.*$"},
+	} {
+		tasks[task.ID] = task
+	}
+	trie := newRouteTrie(tasks)
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		// Priority first, then literal > parameter > wildcard > catch-all;
+		// equal routes are ordered by ID and regex paths come last
+		{"/api/users/me", []string{"priority", "static", "param", "glob-segment", "wildcard", "catch-all", "globstar", "regex"}},
+		// Glob segments are indexed as wildcards; the trie only selects
+		// candidates and the PathMatcher rejects "m?" for "42"
+		{"/api/users/42", []string{"priority", "param", "glob-segment", "wildcard", "catch-all", "globstar", "regex"}},
+		// A longer route wins when the common segments are equally specific
+		{"/api/users/42/posts", []string{"posts", "globstar-suffix", "catch-all", "globstar", "regex"}},
+		// "**" expands to zero or more segments
+		{"/api/posts", []string{"globstar-suffix", "catch-all", "globstar", "regex"}},
+		{"/api/a/b/c/posts", []string{"globstar-suffix", "catch-all", "globstar", "regex"}},
+		{"/api", []string{"catch-all", "globstar", "regex"}},
+		{"/other", []string{"other", "regex"}},
+		{"/missing", []string{"regex"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var got []string
+			for _, entry := range trie.match(tt.path) {
+				got = append(got, entry.task.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteTrieDeduplicatesGlobstarExpansions(t *testing.T) {
+	trie := newRouteTrie(map[string]ProfilingTask{
+		"a": {ID: "a", Path: "/**/x/**"},
+	})
+
+	// "/x/x/x" can be reached through several expansions of both "**"
+	matched := trie.match("/x/x/x")
+	if len(matched) != 1 || matched[0].task.ID != "a" {
+		t.Fatalf("match returned %d entries, want the task once", len(matched))
+	}
+}
+
+func TestRouteEntryBefore(t *testing.T) {
+	entry := func(id string, priority int, kinds ...int) routeEntry {
+		return routeEntry{task: ProfilingTask{ID: id, Priority: priority}, specificity: kinds}
+	}
+
+	tests := []struct {
+		name string
+		a, b routeEntry
+		want bool
+	}{
+		{"higher priority wins over specificity", entry("a", 1, segmentCatchAll), entry("b", 0, segmentStatic), true},
+		{"literal beats parameter", entry("b", 0, segmentStatic, segmentStatic), entry("a", 0, segmentStatic, segmentParam), true},
+		{"parameter beats wildcard", entry("b", 0, segmentParam), entry("a", 0, segmentWildcard), true},
+		{"wildcard beats catch-all", entry("b", 0, segmentWildcard), entry("a", 0, segmentCatchAll), true},
+		{"first differing segment decides", entry("b", 0, segmentStatic, segmentCatchAll), entry("a", 0, segmentParam, segmentStatic), true},
+		{"longer route wins on equal prefix", entry("b", 0, segmentStatic, segmentParam), entry("a", 0, segmentStatic), true},
+		{"ID breaks ties", entry("a", 0, segmentParam), entry("b", 0, segmentParam), true},
+		{"ID tie is not symmetric", entry("b", 0, segmentParam), entry("a", 0, segmentParam), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.before(tt.b); got != tt.want {
+				t.Fatalf("before = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampler 决定匹配任务的请求是否需要分析，实现必须支持并发调用
+type Sampler interface {
+	// Sample 对一次匹配的请求做出采样决定
+	Sample() bool
+}
+
+// NewSampler 根据任务配置创建采样器。配置了多种策略时需全部通过，
+// 依次为每N个请求（sample_rate）、随机概率（sample_probability）、
+// 令牌桶（max_per_minute）和前N次（max_profiles）
+func NewSampler(task ProfilingTask) Sampler {
+	var samplers chainSampler
+
+	if task.SampleRate > 1 {
+		samplers = append(samplers, NewEveryNSampler(task.SampleRate))
+	}
+	if task.SampleProbability > 0 && task.SampleProbability < 1 {
+		samplers = append(samplers, NewProbabilitySampler(task.SampleProbability))
+	}
+	if task.MaxPerMinute > 0 {
+		samplers = append(samplers, NewTokenBucketSampler(task.MaxPerMinute))
+	}
+	if task.MaxProfiles > 0 {
+		// 放在最后，只有其它策略都通过的请求才会占用名额
+		samplers = append(samplers, NewFirstNSampler(task.MaxProfiles))
+	}
+
+	if len(samplers) == 1 {
+		return samplers[0]
+	}
+	return samplers
+}
+
+// chainSampler 组合多个采样器，全部通过时才采样；为空时始终采样
+type chainSampler []Sampler
+
+// Sample 依次询问各采样器，遇到拒绝时立即返回
+func (c chainSampler) Sample() bool {
+	for _, sampler := range c {
+		if !sampler.Sample() {
+			return false
+		}
+	}
+	return true
+}
+
+// EveryNSampler 每N个请求采样一次
+type EveryNSampler struct {
+	n     int64
+	count int64
+}
+
+// NewEveryNSampler 创建每N个请求采样一次的采样器
+func NewEveryNSampler(n int) *EveryNSampler {
+	if n < 1 {
+		n = 1
+	}
+	return &EveryNSampler{n: int64(n)}
+}
+
+// Sample 对请求计数，每第N个请求返回true
+func (s *EveryNSampler) Sample() bool {
+	return atomic.AddInt64(&s.count, 1)%s.n == 0
+}
+
+// ProbabilitySampler 以固定概率随机采样
+type ProbabilitySampler struct {
+	probability float64
+	random      func() float64 // 返回[0, 1)的随机数，测试时可替换
+}
+
+// NewProbabilitySampler 创建随机采样器，probability取值范围为(0, 1]
+func NewProbabilitySampler(probability float64) *ProbabilitySampler {
+	return &ProbabilitySampler{probability: probability, random: rand.Float64}
+}
+
+// Sample 以配置的概率返回true
+func (s *ProbabilitySampler) Sample() bool {
+	return s.random() < s.probability
+}
+
+// TokenBucketSampler 限制每分钟的采样次数，允许突发到每分钟上限
+type TokenBucketSampler struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // 每秒补充的令牌数
+	last     time.Time
+	now      func() time.Time // 当前时间，测试时可替换
+}
+
+// NewTokenBucketSampler 创建每分钟最多采样perMinute次的令牌桶采样器
+func NewTokenBucketSampler(perMinute int) *TokenBucketSampler {
+	capacity := float64(perMinute)
+	return &TokenBucketSampler{
+		capacity: capacity,
+		tokens:   capacity,
+		rate:     capacity / 60,
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// Sample 补充令牌后尝试取出一个令牌
+func (s *TokenBucketSampler) Sample() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.tokens += now.Sub(s.last).Seconds() * s.rate
+	if s.tokens > s.capacity {
+		s.tokens = s.capacity
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// FirstNSampler 只采样前N个请求，之后不再采样
+type FirstNSampler struct {
+	max   int64
+	count int64
+}
+
+// NewFirstNSampler 创建只采样前n个请求的采样器
+func NewFirstNSampler(n int) *FirstNSampler {
+	return &FirstNSampler{max: int64(n)}
+}
+
+// Sample 在名额用完前返回true
+func (s *FirstNSampler) Sample() bool {
+	if atomic.LoadInt64(&s.count) >= s.max {
+		return false
+	}
+	return atomic.AddInt64(&s.count, 1) <= s.max
+}
+
+// samplerConfig 是任务中影响采样的配置，用于判断重新加载后能否沿用原采样器
+type samplerConfig struct {
+	sampleRate        int
+	sampleProbability float64
+	maxPerMinute      int
+	maxProfiles       int
+}
+
+// newSamplerConfig 提取任务的采样配置
+func newSamplerConfig(task ProfilingTask) samplerConfig {
+	return samplerConfig{
+		sampleRate:        task.SampleRate,
+		sampleProbability: task.SampleProbability,
+		maxPerMinute:      task.MaxPerMinute,
+		maxProfiles:       task.MaxProfiles,
+	}
+}
+
+// taskSampler 记录任务的采样器及其创建时的配置
+type taskSampler struct {
+	config  samplerConfig
+	sampler Sampler
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sampleN returns the decisions of n calls to a sampler
+func sampleN(sampler Sampler, n int) []bool {
+	decisions := make([]bool, n)
+	for i := range decisions {
+		decisions[i] = sampler.Sample()
+	}
+	return decisions
+}
+
+func countSampled(decisions []bool) int {
+	count := 0
+	for _, sampled := range decisions {
+		if sampled {
+			count++
+		}
+	}
+	return count
+}
+
+func TestEveryNSampler(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []bool
+	}{
+		{0, []bool{true, true, true}},
+		{1, []bool{true, true, true}},
+		{3, []bool{false, false, true, false, false, true}},
+	}
+	for _, tt := range tests {
+		got := sampleN(NewEveryNSampler(tt.n), len(tt.want))
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("every %d: decisions = %v, want %v", tt.n, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestProbabilitySampler(t *testing.T) {
+	sampler := NewProbabilitySampler(0.25)
+	randoms := []float64{0, 0.1, 0.2499, 0.25, 0.5, 0.9999}
+	want := []bool{true, true, true, false, false, false}
+
+	next := 0
+	sampler.random = func() float64 {
+		next++
+		return randoms[next-1]
+	}
+	got := sampleN(sampler, len(randoms))
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("decisions = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTokenBucketSampler(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sampler := NewTokenBucketSampler(6)
+	sampler.last = now
+	sampler.now = func() time.Time { return now }
+
+	// The bucket starts full and allows a burst of the per-minute limit
+	if got := countSampled(sampleN(sampler, 10)); got != 6 {
+		t.Fatalf("burst sampled %d requests, want 6", got)
+	}
+
+	// 6 per minute refills a token every 10 seconds
+	now = now.Add(9 * time.Second)
+	if sampler.Sample() {
+		t.Fatal("sampled before a token was refilled")
+	}
+	now = now.Add(time.Second)
+	if !sampler.Sample() || sampler.Sample() {
+		t.Fatal("want exactly one sample after one token was refilled")
+	}
+
+	// Refilling stops at the capacity
+	now = now.Add(time.Hour)
+	if got := countSampled(sampleN(sampler, 10)); got != 6 {
+		t.Fatalf("sampled %d requests after an hour, want 6", got)
+	}
+}
+
+func TestFirstNSampler(t *testing.T) {
+	got := sampleN(NewFirstNSampler(2), 5)
+	want := []bool{true, true, false, false, false}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("decisions = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNewSamplerChain(t *testing.T) {
+	// Every second request, of which only the first two are sampled
+	sampler := NewSampler(ProfilingTask{SampleRate: 2, MaxProfiles: 2})
+	got := sampleN(sampler, 8)
+	want := []bool{false, true, false, true, false, false, false, false}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("decisions = %v, want %v", got, want)
+		}
+	}
+
+	// Without a strategy every request is sampled
+	if got := countSampled(sampleN(NewSampler(ProfilingTask{SampleRate: 1}), 5)); got != 5 {
+		t.Fatalf("sampled %d of 5 requests without a strategy", got)
+	}
+}
+
+func TestSamplersConcurrent(t *testing.T) {
+	const goroutines, calls = 8, 1000
+
+	// The bucket is not refilled while the test runs
+	bucket := NewTokenBucketSampler(100)
+	frozen := bucket.last
+	bucket.now = func() time.Time { return frozen }
+
+	tests := []struct {
+		name    string
+		sampler Sampler
+		want    int
+	}{
+		{"every n", NewEveryNSampler(10), goroutines * calls / 10},
+		{"first n", NewFirstNSampler(100), 100},
+		{"token bucket", bucket, 100},
+		{"chain", NewSampler(ProfilingTask{SampleRate: 2, MaxProfiles: 50}), 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sampled int64
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < calls; j++ {
+						if tt.sampler.Sample() {
+							atomic.AddInt64(&sampled, 1)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			if sampled != int64(tt.want) {
+				t.Fatalf("sampled %d requests, want %d", sampled, tt.want)
+			}
+		})
+	}
+
+	// The probability sampler only has to be safe to call concurrently
+	sampler := NewSampler(ProfilingTask{SampleProbability: 0.5})
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sampleN(sampler, calls)
+		}()
+	}
+	wg.Wait()
+}
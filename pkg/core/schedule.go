@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ScheduleWindow 表示一个计划分析窗口
+type ScheduleWindow struct {
+	Start  time.Time `json:"start"`  // 窗口开始时间
+	End    time.Time `json:"end"`    // 窗口结束时间
+	Active bool      `json:"active"` // 当前是否处于该窗口内
+}
+
+// TaskSchedule 是解析后的任务分析计划：cron表达式给出窗口开始时间，
+// 每个窗口持续Window时长
+type TaskSchedule struct {
+	schedule cron.Schedule
+	window   time.Duration
+	location *time.Location
+}
+
+// NewTaskSchedule 解析任务的schedule、window和timezone，任务未配置计划时返回nil
+func NewTaskSchedule(task ProfilingTask) (*TaskSchedule, error) {
+	if task.Schedule == "" {
+		return nil, nil
+	}
+
+	schedule, err := cron.ParseStandard(task.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", task.Schedule, err)
+	}
+	if task.Window <= 0 {
+		return nil, fmt.Errorf("schedule %q requires a positive window", task.Schedule)
+	}
+
+	location := time.UTC
+	if task.Timezone != "" {
+		if location, err = time.LoadLocation(task.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", task.Timezone, err)
+		}
+	}
+
+	return &TaskSchedule{schedule: schedule, window: task.Window, location: location}, nil
+}
+
+// Active 检查给定时间是否处于某个计划窗口内
+func (s *TaskSchedule) Active(now time.Time) bool {
+	return s.Window(now).Active
+}
+
+// Window 返回包含给定时间的窗口，不在窗口内时返回下一个窗口
+func (s *TaskSchedule) Window(now time.Time) ScheduleWindow {
+	// 第一个晚于 now-window 的开始时间，若不晚于now则now处于该窗口内
+	start := s.schedule.Next(now.In(s.location).Add(-s.window))
+	if start.IsZero() {
+		return ScheduleWindow{}
+	}
+	return ScheduleWindow{
+		Start:  start,
+		End:    start.Add(s.window),
+		Active: !start.After(now),
+	}
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// 文件元数据的键
+const (
+	// MetadataContentType 文件内容类型
+	MetadataContentType = "content_type"
+	// MetadataProfileType 文件对应的分析类型
+	MetadataProfileType = "profile_type"
+	// MetadataContentEncoding 存储时使用的压缩编码，如 "gzip" 或 "zstd"
+	MetadataContentEncoding = "content_encoding"
+)
+
+// 分析文件的内容类型
+const (
+	// ContentTypePprof gzip压缩的pprof protobuf
+	ContentTypePprof = "application/vnd.google.protobuf+gzip"
+	// ContentTypeTrace runtime/trace的执行追踪
+	ContentTypeTrace = "application/octet-stream"
+	// ContentTypeJSON 分析文件的元数据记录
+	ContentTypeJSON = "application/json"
+)
+
+// FileInfo 描述存储中的文件
+type FileInfo struct {
+	Name       string            `json:"name"`                  // 文件名，与Save时一致
+	Size       int64             `json:"size"`                  // 文件大小（字节）
+	StoredSize int64             `json:"stored_size,omitempty"` // 存储中实际占用的字节数，如压缩后的大小，为0时与Size相同
+	ModTime    time.Time         `json:"mod_time"`              // 修改时间
+	Metadata   map[string]string `json:"metadata,omitempty"`    // 元数据
+}
+
+// FileMetadata 根据文件名推断文件的元数据：内容类型由扩展名决定，
+// 分析类型为文件名的第一级目录
+func FileMetadata(filename string) map[string]string {
+	metadata := make(map[string]string)
+	switch path.Ext(filename) {
+	case ".pprof":
+		metadata[MetadataContentType] = ContentTypePprof
+	case ".trace":
+		metadata[MetadataContentType] = ContentTypeTrace
+	case MetadataExtension:
+		metadata[MetadataContentType] = ContentTypeJSON
+	}
+	if dir, _, found := strings.Cut(path.Clean(filename), "/"); found {
+		metadata[MetadataProfileType] = dir
+	}
+	return metadata
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trigger token modes
+const (
+	// TriggerModeSecret compares the token with the shared secret
+	TriggerModeSecret = "secret"
+	// TriggerModeHMAC verifies a timestamped HMAC-SHA256 signature of the request
+	TriggerModeHMAC = "hmac"
+)
+
+// SourceTrigger marks tasks created by an on-demand trigger
+const SourceTrigger = "trigger"
+
+// ProfileIDHeader is the response header carrying the profile ID of a triggered request
+const ProfileIDHeader = "X-Gin-Pprof-Id"
+
+// TriggerOptions configures on-demand profiling of a single request via a
+// request header or query parameter (e.g. "X-Gin-Pprof: cpu")
+type TriggerOptions struct {
+	// Enabled turns on the trigger mode. Triggers are ignored unless Secret is set.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Header carries the requested profile type
+	Header string `yaml:"header" json:"header"`
+
+	// TokenHeader carries the secret or HMAC token
+	TokenHeader string `yaml:"token_header" json:"token_header"`
+
+	// QueryParam carries the requested profile type, empty disables query triggers
+	QueryParam string `yaml:"query_param" json:"query_param"`
+
+	// TokenQueryParam carries the token for query triggers
+	TokenQueryParam string `yaml:"token_query_param" json:"token_query_param"`
+
+	// Mode is either "secret" or "hmac"
+	Mode string `yaml:"mode" json:"mode"`
+
+	// Secret is the shared secret or HMAC key
+	Secret string `yaml:"secret" json:"-"`
+
+	// MaxSkew is the maximum age of an HMAC token
+	MaxSkew time.Duration `yaml:"max_skew" json:"max_skew"`
+
+	// Duration is the maximum profiling duration of a triggered request
+	Duration time.Duration `yaml:"duration" json:"duration"`
+}
+
+// DefaultTriggerOptions returns default trigger options (disabled)
+func DefaultTriggerOptions() TriggerOptions {
+	return TriggerOptions{
+		Enabled:         false,
+		Header:          "X-Gin-Pprof",
+		TokenHeader:     "X-Gin-Pprof-Token",
+		TokenQueryParam: "gin_pprof_token",
+		Mode:            TriggerModeSecret,
+		MaxSkew:         5 * time.Minute,
+		Duration:        30 * time.Second,
+	}
+}
+
+// SignTriggerToken creates an HMAC trigger token for a request. The token has
+// the form "<unix timestamp>:<hex signature>".
+func SignTriggerToken(secret, profileType, method, requestPath string, at time.Time) string {
+	ts := at.Unix()
+	return strconv.FormatInt(ts, 10) + ":" + triggerSignature(secret, profileType, method, requestPath, ts)
+}
+
+// triggerSignature computes the HMAC-SHA256 signature of a trigger
+func triggerSignature(secret, profileType, method, requestPath string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d", profileType, strings.ToUpper(method), requestPath, ts)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readTrigger returns the requested profile type and token from the request
+func (o *TriggerOptions) readTrigger(httpCtx HTTPContext) (profileType, token string) {
+	headers := httpCtx.GetHeaders()
+	if o.Header != "" {
+		if profileType = headerValue(headers, o.Header); profileType != "" {
+			return strings.TrimSpace(profileType), headerValue(headers, o.TokenHeader)
+		}
+	}
+
+	if o.QueryParam != "" {
+		if profileType = httpCtx.GetQuery(o.QueryParam); profileType != "" {
+			token = headerValue(headers, o.TokenHeader)
+			if token == "" && o.TokenQueryParam != "" {
+				token = httpCtx.GetQuery(o.TokenQueryParam)
+			}
+			return strings.TrimSpace(profileType), token
+		}
+	}
+
+	return "", ""
+}
+
+// verifyToken checks the trigger token against the configured secret
+func (o *TriggerOptions) verifyToken(token, profileType, method, requestPath string, now time.Time) bool {
+	if o.Secret == "" || token == "" {
+		return false
+	}
+
+	switch o.Mode {
+	case TriggerModeHMAC:
+		parts := strings.SplitN(token, ":", 2)
+		if len(parts) != 2 {
+			return false
+		}
+		ts, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		skew := now.Sub(time.Unix(ts, 0))
+		if skew < 0 {
+			skew = -skew
+		}
+		if o.MaxSkew > 0 && skew > o.MaxSkew {
+			return false
+		}
+		expected := triggerSignature(o.Secret, profileType, method, requestPath, ts)
+		return hmac.Equal([]byte(parts[1]), []byte(expected))
+	default:
+		return subtle.ConstantTimeCompare([]byte(token), []byte(o.Secret)) == 1
+	}
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	if name == "" {
+		return ""
+	}
+	if value, exists := headers[name]; exists {
+		return value
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
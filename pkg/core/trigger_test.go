@@ -0,0 +1,145 @@
+package core
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeHTTPContext is a request with fixed route, headers and query
+type fakeHTTPContext struct {
+	path        string
+	method      string
+	requestPath string
+	headers     map[string]string
+	query       map[string]string
+}
+
+func (c fakeHTTPContext) GetPath() string                        { return c.path }
+func (c fakeHTTPContext) GetMethod() string                      { return c.method }
+func (c fakeHTTPContext) GetHeaders() map[string]string          { return c.headers }
+func (c fakeHTTPContext) SetContext(key, value interface{})      {}
+func (c fakeHTTPContext) GetContext(key interface{}) interface{} { return nil }
+func (c fakeHTTPContext) GetRequestPath() string                 { return c.requestPath }
+func (c fakeHTTPContext) GetQuery(key string) string             { return c.query[key] }
+
+func TestTriggerVerifyToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sign := func(profileType, method, requestPath string, at time.Time) string {
+		return SignTriggerToken("s3cret", profileType, method, requestPath, at)
+	}
+	hmacOptions := TriggerOptions{Mode: TriggerModeHMAC, Secret: "s3cret", MaxSkew: 5 * time.Minute}
+
+	tests := []struct {
+		name    string
+		options TriggerOptions
+		token   string
+		want    bool
+	}{
+		{"secret", TriggerOptions{Mode: TriggerModeSecret, Secret: "s3cret"}, "s3cret", true},
+		{"wrong secret", TriggerOptions{Mode: TriggerModeSecret, Secret: "s3cret"}, "s3creT", false},
+		{"secret prefix", TriggerOptions{Mode: TriggerModeSecret, Secret: "s3cret"}, "s3cre", false},
+		{"secret with suffix", TriggerOptions{Mode: TriggerModeSecret, Secret: "s3cret"}, "s3cret!", false},
+		{"empty token", TriggerOptions{Mode: TriggerModeSecret, Secret: "s3cret"}, "", false},
+		{"no secret configured", TriggerOptions{Mode: TriggerModeSecret}, "", false},
+		{"unknown mode compares the secret", TriggerOptions{Secret: "s3cret"}, "s3cret", true},
+
+		{"hmac", hmacOptions, sign("cpu", "GET", "/api/users/1", now), true},
+		{"hmac lower-case method", hmacOptions, sign("cpu", "get", "/api/users/1", now), true},
+		{"hmac within skew", hmacOptions, sign("cpu", "GET", "/api/users/1", now.Add(-4*time.Minute)), true},
+		{"hmac future within skew", hmacOptions, sign("cpu", "GET", "/api/users/1", now.Add(4*time.Minute)), true},
+		{"hmac too old", hmacOptions, sign("cpu", "GET", "/api/users/1", now.Add(-6*time.Minute)), false},
+		{"hmac too far in the future", hmacOptions, sign("cpu", "GET", "/api/users/1", now.Add(6*time.Minute)), false},
+		{"hmac without skew limit", TriggerOptions{Mode: TriggerModeHMAC, Secret: "s3cret"}, sign("cpu", "GET", "/api/users/1", now.Add(-24*time.Hour)), true},
+		{"hmac other profile type", hmacOptions, sign("heap", "GET", "/api/users/1", now), false},
+		{"hmac other method", hmacOptions, sign("cpu", "POST", "/api/users/1", now), false},
+		{"hmac other path", hmacOptions, sign("cpu", "GET", "/api/users/2", now), false},
+		{"hmac other secret", hmacOptions, SignTriggerToken("other", "cpu", "GET", "/api/users/1", now), false},
+		{"hmac secret as token", hmacOptions, "s3cret", false},
+		{"hmac bad timestamp", hmacOptions, "yesterday:" + triggerSignature("s3cret", "cpu", "GET", "/api/users/1", now.Unix()), false},
+		{"hmac timestamp changed", hmacOptions, strconv.FormatInt(now.Unix()+1, 10) + ":" + triggerSignature("s3cret", "cpu", "GET", "/api/users/1", now.Unix()), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.verifyToken(tt.token, "cpu", "GET", "/api/users/1", now); got != tt.want {
+				t.Fatalf("verifyToken(%q) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerReadTrigger(t *testing.T) {
+	options := DefaultTriggerOptions()
+	options.QueryParam = "gin_pprof"
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		query     map[string]string
+		wantType  string
+		wantToken string
+	}{
+		{"header", map[string]string{"X-Gin-Pprof": " cpu ", "X-Gin-Pprof-Token": "t"}, nil, "cpu", "t"},
+		{"header case-insensitive", map[string]string{"x-gin-pprof": "heap", "x-gin-pprof-token": "t"}, nil, "heap", "t"},
+		{"header ignores query token", map[string]string{"X-Gin-Pprof": "cpu"}, map[string]string{"gin_pprof_token": "q"}, "cpu", ""},
+		{"query with query token", nil, map[string]string{"gin_pprof": "cpu", "gin_pprof_token": "q"}, "cpu", "q"},
+		{"query prefers header token", map[string]string{"X-Gin-Pprof-Token": "h"}, map[string]string{"gin_pprof": "cpu", "gin_pprof_token": "q"}, "cpu", "h"},
+		{"header wins over query", map[string]string{"X-Gin-Pprof": "heap"}, map[string]string{"gin_pprof": "cpu"}, "heap", ""},
+		{"no trigger", map[string]string{"X-Gin-Pprof-Token": "t"}, map[string]string{"gin_pprof_token": "q"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profileType, token := options.readTrigger(fakeHTTPContext{headers: tt.headers, query: tt.query})
+			if profileType != tt.wantType || token != tt.wantToken {
+				t.Fatalf("readTrigger = %q, %q, want %q, %q", profileType, token, tt.wantType, tt.wantToken)
+			}
+		})
+	}
+
+	// Query triggers are disabled without QueryParam
+	disabled := DefaultTriggerOptions()
+	if profileType, _ := disabled.readTrigger(fakeHTTPContext{query: map[string]string{"gin_pprof": "cpu"}}); profileType != "" {
+		t.Fatalf("query trigger accepted without QueryParam: %q", profileType)
+	}
+}
+
+func TestManagerTriggeredTask(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Trigger.Enabled = true
+	opts.Trigger.Mode = TriggerModeHMAC
+	opts.Trigger.Secret = "s3cret"
+	opts.Trigger.QueryParam = "gin_pprof"
+	m := newTestManager(t, &staticProvider{tasks: []ProfilingTask{validTask("/other", "cpu")}}, nopStorage{}, opts)
+
+	request := func(profileType, token string) fakeHTTPContext {
+		return fakeHTTPContext{
+			path:        "/api/users/:id",
+			method:      "GET",
+			requestPath: "/api/users/1",
+			query:       map[string]string{"gin_pprof": profileType, "gin_pprof_token": token},
+		}
+	}
+	valid := SignTriggerToken("s3cret", "goroutine", "GET", "/api/users/1", time.Now())
+
+	task, ok := m.ShouldProfileRequest(request("goroutine", valid))
+	if !ok {
+		t.Fatal("valid trigger was rejected")
+	}
+	m.releaseLimiter()
+	if task.Source != SourceTrigger || task.ProfileType != "goroutine" || task.Path != "/api/users/:id" {
+		t.Fatalf("triggered task = %+v", task)
+	}
+
+	stale := SignTriggerToken("s3cret", "goroutine", "GET", "/api/users/1", time.Now().Add(-time.Hour))
+	for name, req := range map[string]fakeHTTPContext{
+		"stale token":          request("goroutine", stale),
+		"token of other type":  request("cpu", valid),
+		"unknown profile type": request("nope", SignTriggerToken("s3cret", "nope", "GET", "/api/users/1", time.Now())),
+	} {
+		if _, ok := m.ShouldProfileRequest(req); ok {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+}
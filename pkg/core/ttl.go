@@ -0,0 +1,55 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// FirstSeenTracker 记录配置提供器首次加载每个任务的时间，
+// 使相对的ttl在重新加载同一配置时不会被重置
+type FirstSeenTracker struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewFirstSeenTracker 创建首次加载时间记录器
+func NewFirstSeenTracker() *FirstSeenTracker {
+	return &FirstSeenTracker{seen: make(map[string]time.Time)}
+}
+
+// Resolve 将任务的ttl转换为绝对的expires_at。ttl从首次加载时间（或更晚的starts_at）
+// 开始计算，同时配置了expires_at时取较早者。任务以ID识别（未指定时使用生成的ID），
+// 不再出现在配置中的任务会被遗忘
+func (t *FirstSeenTracker) Resolve(tasks []ProfilingTask, now time.Time) []ProfilingTask {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[string]time.Time, len(tasks))
+	resolved := make([]ProfilingTask, len(tasks))
+	for i, task := range tasks {
+		key := task.ID
+		if key == "" {
+			key = task.GenerateID()
+		}
+
+		firstSeen, exists := t.seen[key]
+		if !exists {
+			firstSeen = now
+		}
+		seen[key] = firstSeen
+
+		if task.TTL > 0 {
+			start := firstSeen
+			if task.StartsAt.After(start) {
+				start = task.StartsAt
+			}
+			if expiresAt := start.Add(task.TTL); task.ExpiresAt.IsZero() || expiresAt.Before(task.ExpiresAt) {
+				task.ExpiresAt = expiresAt
+			}
+		}
+		resolved[i] = task
+	}
+
+	t.seen = seen
+	return resolved
+}
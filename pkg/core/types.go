@@ -1,17 +1,72 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
 	"time"
 )
 
 // ProfilingTask 表示性能分析任务配置
 type ProfilingTask struct {
-	Path        string    `yaml:"path" json:"path"`                 // 路径
-	Methods     []string  `yaml:"methods" json:"methods"`           // HTTP方法数组，支持多个方法或使用"*"表示常用方法
-	ExpiresAt   time.Time `yaml:"expires_at" json:"expires_at"`     // 过期时间
-	Duration    int       `yaml:"duration" json:"duration"`         // 最大分析持续时间(秒)
-	SampleRate  int       `yaml:"sample_rate" json:"sample_rate"`   // 每N个请求进行采样
-	ProfileType string    `yaml:"profile_type" json:"profile_type"` // cpu, heap, goroutine等
+	ID                string        `yaml:"id,omitempty" json:"id"`                                           // 任务ID，未指定时根据路径、方法和分析类型生成
+	Path              string        `yaml:"path" json:"path"`                                                 // 路径
+	Methods           []string      `yaml:"methods" json:"methods"`                                           // HTTP方法数组，支持多个方法或使用"*"表示常用方法
+	ExpiresAt         time.Time     `yaml:"expires_at" json:"expires_at"`                                     // 过期时间
+	StartsAt          time.Time     `yaml:"starts_at,omitempty" json:"starts_at,omitempty"`                   // 开始时间，之前不分析
+	TTL               time.Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`                               // 相对过期时间，从配置首次加载时开始计算，如30m
+	Schedule          string        `yaml:"schedule,omitempty" json:"schedule,omitempty"`                     // cron表达式，配置后仅在计划窗口内分析，如"0 2 * * *"
+	Window            time.Duration `yaml:"window,omitempty" json:"window,omitempty"`                         // 每个计划窗口的时长，如1h
+	Timezone          string        `yaml:"timezone,omitempty" json:"timezone,omitempty"`                     // 计划使用的时区，默认UTC
+	Duration          int           `yaml:"duration" json:"duration"`                                         // 最大分析持续时间(秒)
+	SampleRate        int           `yaml:"sample_rate" json:"sample_rate"`                                   // 每N个请求进行采样
+	SampleProbability float64       `yaml:"sample_probability,omitempty" json:"sample_probability,omitempty"` // 按概率随机采样，如0.01
+	MaxPerMinute      int           `yaml:"max_per_minute,omitempty" json:"max_per_minute,omitempty"`         // 每分钟最多采样次数（令牌桶）
+	MaxProfiles       int           `yaml:"max_profiles,omitempty" json:"max_profiles,omitempty"`             // 只采样前N个请求
+	MaxCaptures       int           `yaml:"max_captures,omitempty" json:"max_captures,omitempty"`             // 保存N个分析文件后停止分析，0表示不限制
+	MaxTotalBytes     int64         `yaml:"max_total_bytes,omitempty" json:"max_total_bytes,omitempty"`       // 保存的分析文件总大小达到该值后停止分析，0表示不限制
+	ProfileType       string        `yaml:"profile_type" json:"profile_type"`                                 // cpu, heap, goroutine等
+	Priority          int           `yaml:"priority,omitempty" json:"priority,omitempty"`                     // 优先级，多个任务匹配同一请求时数值大者优先
+	MinLatency        time.Duration `yaml:"min_latency,omitempty" json:"min_latency,omitempty"`               // 仅保留耗时不低于该值的请求，0表示不限制
+	MaxLatency        time.Duration `yaml:"max_latency,omitempty" json:"max_latency,omitempty"`               // 仅保留耗时不高于该值的请求，0表示不限制
+	KeepOn            *KeepOnRules  `yaml:"keep_on,omitempty" json:"keep_on,omitempty"`                       // 按状态码和错误保留分析数据的规则
+	Source            string        `yaml:"-" json:"source,omitempty"`                                        // 任务来源，如"file"、"nacos"、"runtime"，按需触发的任务为"trigger"
+}
+
+// GenerateID 根据路径、方法和分析类型生成稳定的任务ID
+func (task *ProfilingTask) GenerateID() string {
+	methods := task.GetEffectiveMethods()
+	normalized := make([]string, len(methods))
+	for i, method := range methods {
+		normalized[i] = strings.ToUpper(method)
+	}
+	sort.Strings(normalized)
+
+	sum := sha256.Sum256([]byte(task.Path + "|" + strings.Join(normalized, ",") + "|" + task.ProfileType))
+	return "task-" + hex.EncodeToString(sum[:6])
+}
+
+// ShouldKeepLatency 检查请求耗时是否满足任务的保留阈值
+func (task *ProfilingTask) ShouldKeepLatency(latency time.Duration) bool {
+	if task.MinLatency > 0 && latency < task.MinLatency {
+		return false
+	}
+	if task.MaxLatency > 0 && latency > task.MaxLatency {
+		return false
+	}
+	return true
+}
+
+// QuotaExhausted 检查已保存的分析数量或总大小是否达到任务配额
+func (task *ProfilingTask) QuotaExhausted(captures, capturedBytes int64) bool {
+	if task.MaxCaptures > 0 && captures >= int64(task.MaxCaptures) {
+		return true
+	}
+	if task.MaxTotalBytes > 0 && capturedBytes >= task.MaxTotalBytes {
+		return true
+	}
+	return false
 }
 
 // ProfilingStats 表示性能分析统计信息
@@ -19,18 +74,56 @@ type ProfilingStats struct {
 	TotalRequests  int64     `json:"total_requests"`  // 总请求数
 	ProfiledCount  int64     `json:"profiled_count"`  // 已分析数量
 	FailedCount    int64     `json:"failed_count"`    // 失败数量
+	KeptCount      int64     `json:"kept_count"`      // 已保存的分析数量
+	DiscardedCount int64     `json:"discarded_count"` // 因不满足保留条件而丢弃的数量
 	ActiveProfiles int64     `json:"active_profiles"` // 活跃分析数
 	LastUpdate     time.Time `json:"last_update"`     // 最后更新时间
 }
 
-// ProfilingResult 表示性能分析会话的结果
+// TaskStats 表示单个任务的性能分析统计信息
+type TaskStats struct {
+	TaskID         string `json:"task_id"`          // 任务ID
+	Path           string `json:"path"`             // 路径
+	ProfileType    string `json:"profile_type"`     // 分析类型
+	Source         string `json:"source,omitempty"` // 任务来源
+	MatchedCount   int64  `json:"matched_count"`    // 匹配的请求数
+	ProfiledCount  int64  `json:"profiled_count"`   // 已分析数量
+	FailedCount    int64  `json:"failed_count"`     // 失败数量
+	KeptCount      int64  `json:"kept_count"`       // 已保存的分析数量
+	DiscardedCount int64  `json:"discarded_count"`  // 因不满足保留条件而丢弃的数量
+	CapturedBytes  int64  `json:"captured_bytes"`   // 已保存的分析文件总大小
+}
+
+// TaskStatus 表示任务及其配额使用情况
+type TaskStatus struct {
+	ProfilingTask
+	Captures      int64           `json:"captures"`              // 已保存的分析数量
+	CapturedBytes int64           `json:"captured_bytes"`        // 已保存的分析文件总大小
+	Exhausted     bool            `json:"exhausted"`             // 是否已达到配额
+	NextWindow    *ScheduleWindow `json:"next_window,omitempty"` // 当前或下一个计划窗口，未配置计划时为空
+}
+
+// ProfilingResult 表示性能分析会话的结果，保存的分析文件旁会写入同样内容的元数据记录
 type ProfilingResult struct {
-	Path        string        `json:"path"`         // 路径
-	StartTime   time.Time     `json:"start_time"`   // 开始时间
-	Duration    time.Duration `json:"duration"`     // 持续时间
-	Filename    string        `json:"filename"`     // 文件名
-	FileSize    int64         `json:"file_size"`    // 文件大小
-	ProfileType string        `json:"profile_type"` // 分析类型
-	Success     bool          `json:"success"`      // 是否成功
-	Error       string        `json:"error,omitempty"` // 错误信息
-}
\ No newline at end of file
+	ProfileID       string            `json:"profile_id"`                 // 分析ID，对应请求上的pprof标签
+	TaskID          string            `json:"task_id"`                    // 任务ID
+	Source          string            `json:"source,omitempty"`           // 任务来源
+	Path            string            `json:"path"`                       // 路由模板
+	Method          string            `json:"method"`                     // HTTP方法
+	RequestPath     string            `json:"request_path,omitempty"`     // 实际请求路径
+	PathParams      map[string]string `json:"path_params,omitempty"`      // 路径参数
+	Hostname        string            `json:"hostname,omitempty"`         // 主机名
+	StartTime       time.Time         `json:"start_time"`                 // 开始时间
+	Duration        time.Duration     `json:"duration"`                   // 持续时间，即请求耗时
+	Filename        string            `json:"filename"`                   // 文件名
+	FileSize        int64             `json:"file_size"`                  // 文件大小
+	StoredSize      int64             `json:"stored_size,omitempty"`      // 存储中占用的字节数，压缩时为压缩后的大小
+	ContentEncoding string            `json:"content_encoding,omitempty"` // 存储时使用的压缩编码，未压缩时为空
+	ProfileType     string            `json:"profile_type"`               // 分析类型
+	Success         bool              `json:"success"`                    // 是否成功
+	Discarded       bool              `json:"discarded"`                  // 是否因不满足保留条件而丢弃
+	StatusCode      int               `json:"status_code"`                // 请求最终状态码
+	HasErrors       bool              `json:"has_errors,omitempty"`       // 处理过程中是否附加了错误
+	Panicked        bool              `json:"panicked,omitempty"`         // 处理函数是否发生panic
+	Error           string            `json:"error,omitempty"`            // 错误信息
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// knownMethods 是任务methods字段允许的HTTP方法
+var knownMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace, "*",
+}
+
+// profileTypes 记录已知的分析类型，内置类型之外的类型在注册分析器时加入
+var profileTypes = struct {
+	sync.RWMutex
+	types map[string]bool
+}{types: map[string]bool{
+	"cpu": true, "heap": true, "heap_delta": true, "allocs_delta": true,
+	"goroutine": true, "mutex": true, "block": true, "trace": true,
+}}
+
+// registerProfileType 将分析类型加入已知类型
+func registerProfileType(profileType string) {
+	profileTypes.Lock()
+	defer profileTypes.Unlock()
+	profileTypes.types[profileType] = true
+}
+
+// IsKnownProfileType 检查分析类型是否为内置类型或已注册的自定义类型
+func IsKnownProfileType(profileType string) bool {
+	profileTypes.RLock()
+	defer profileTypes.RUnlock()
+	return profileTypes.types[profileType]
+}
+
+// FieldError 描述单个字段的问题
+type FieldError struct {
+	Field   string `json:"field"`   // 字段名，与配置文件中的键一致
+	Message string `json:"message"` // 问题描述
+}
+
+// String 返回"字段: 问题"格式的描述
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Message
+}
+
+// TaskValidationError 描述任务配置中的所有问题
+type TaskValidationError struct {
+	TaskID string       `json:"id,omitempty"` // 任务ID
+	Path   string       `json:"path"`         // 路径
+	Errors []FieldError `json:"errors"`       // 字段问题列表
+}
+
+// Error 返回包含所有问题的错误信息
+func (e *TaskValidationError) Error() string {
+	problems := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		problems[i] = fieldErr.String()
+	}
+	return fmt.Sprintf("invalid task %q: %s", e.Path, strings.Join(problems, "; "))
+}
+
+// Validate 检查任务配置是否有效，存在问题时返回*TaskValidationError，
+// 其中列出每个出错的字段
+func (task *ProfilingTask) Validate() error {
+	var errs []FieldError
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case strings.TrimSpace(task.Path) == "":
+		add("path", "is required")
+	case strings.HasPrefix(task.Path, RegexPathPrefix):
+		if _, err := regexp.Compile(strings.TrimPrefix(task.Path, RegexPathPrefix)); err != nil {
+			add("path", "invalid regex: %v", err)
+		}
+	case !strings.HasPrefix(task.Path, "/"):
+		add("path", "must start with \"/\" or %q", RegexPathPrefix)
+	case strings.ContainsAny(task.Path, " \t\r\n?#"):
+		add("path", "must not contain whitespace, query or fragment")
+	default:
+		for _, segment := range splitPath(task.Path) {
+			if _, err := path.Match(segment, ""); err != nil {
+				add("path", "invalid glob segment %q", segment)
+			}
+		}
+	}
+
+	for i, method := range task.Methods {
+		if !contains(knownMethods, method) {
+			add(fmt.Sprintf("methods[%d]", i), "unknown method %q", method)
+		}
+	}
+
+	if task.ProfileType == "" {
+		add("profile_type", "is required")
+	} else if !IsKnownProfileType(task.ProfileType) {
+		add("profile_type", "unknown profile type %q", task.ProfileType)
+	}
+
+	if task.ExpiresAt.IsZero() && task.TTL <= 0 {
+		add("expires_at", "is required when ttl is not set")
+	}
+	if task.TTL < 0 {
+		add("ttl", "must not be negative")
+	}
+	if !task.StartsAt.IsZero() && !task.ExpiresAt.IsZero() && !task.StartsAt.Before(task.ExpiresAt) {
+		add("starts_at", "must be before expires_at")
+	}
+
+	if task.Duration < 0 {
+		add("duration", "must not be negative")
+	}
+	if task.SampleRate < 0 {
+		add("sample_rate", "must not be negative")
+	}
+	if task.SampleProbability < 0 || task.SampleProbability > 1 {
+		add("sample_probability", "must be between 0 and 1")
+	}
+	if task.MaxPerMinute < 0 {
+		add("max_per_minute", "must not be negative")
+	}
+	if task.MaxProfiles < 0 {
+		add("max_profiles", "must not be negative")
+	}
+	if task.MaxCaptures < 0 {
+		add("max_captures", "must not be negative")
+	}
+	if task.MaxTotalBytes < 0 {
+		add("max_total_bytes", "must not be negative")
+	}
+
+	if task.MinLatency < 0 {
+		add("min_latency", "must not be negative")
+	}
+	if task.MaxLatency < 0 {
+		add("max_latency", "must not be negative")
+	} else if task.MaxLatency > 0 && task.MinLatency > task.MaxLatency {
+		add("min_latency", "must not exceed max_latency")
+	}
+
+	if task.KeepOn != nil {
+		for i, statusRange := range task.KeepOn.StatusRanges {
+			if _, _, ok := ParseStatusRange(statusRange); !ok {
+				add(fmt.Sprintf("keep_on.status_ranges[%d]", i), "invalid status range %q", statusRange)
+			}
+		}
+	}
+
+	if task.Schedule != "" {
+		if _, err := cron.ParseStandard(task.Schedule); err != nil {
+			add("schedule", "invalid cron expression: %v", err)
+		}
+		if task.Window <= 0 {
+			add("window", "must be positive when schedule is set")
+		}
+	}
+	if task.Timezone != "" {
+		if _, err := time.LoadLocation(task.Timezone); err != nil {
+			add("timezone", "unknown timezone %q", task.Timezone)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &TaskValidationError{TaskID: task.ID, Path: task.Path, Errors: errs}
+}
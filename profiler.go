@@ -1,8 +1,12 @@
 package ginpprof
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/aclstack/gin-pprof/pkg/adapters/config"
@@ -16,6 +20,8 @@ import (
 // Profiler is the main profiler instance
 type Profiler struct {
 	manager *core.Manager
+	runtime *config.RuntimeConfig
+	storage core.Storage
 	logger  core.Logger
 	options core.Options
 }
@@ -27,6 +33,8 @@ type Builder struct {
 	storage        core.Storage
 	logger         core.Logger
 	pathMatcher    core.PathMatcher
+	runtimeFile    string
+	profilers      []core.Profiler
 }
 
 // New creates a new profiler builder
@@ -64,6 +72,13 @@ func (b *Builder) WithNacosConfig(opts config.NacosOptions) *Builder {
 	return b
 }
 
+// WithConfigProvider sets a custom config provider, e.g. a
+// config.MultiConfigProvider that merges several task sources
+func (b *Builder) WithConfigProvider(provider core.ConfigProvider) *Builder {
+	b.configProvider = provider
+	return b
+}
+
 // WithFileStorage configures file-based storage
 func (b *Builder) WithFileStorage(baseDir string) *Builder {
 	fileLogger := b.getOrCreateFileLogger()
@@ -88,6 +103,29 @@ func (b *Builder) WithFileStorage(baseDir string) *Builder {
 	return b
 }
 
+// WithS3Storage configures S3-compatible object storage (AWS S3, MinIO, ...)
+func (b *Builder) WithS3Storage(opts storage.S3Options) *Builder {
+	fileLogger := b.getOrCreateFileLogger()
+
+	s3Storage, err := storage.NewS3Storage(opts, fileLogger)
+	if err != nil {
+		fileLogger.Error("Failed to create S3 storage", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return b
+	}
+
+	b.storage = s3Storage
+	return b
+}
+
+// WithStorage sets a custom storage, typically a storage wrapped in
+// decorators such as storage.CompressingStorage
+func (b *Builder) WithStorage(s core.Storage) *Builder {
+	b.storage = s
+	return b
+}
+
 // WithMemoryStorage configures memory-based storage (for testing)
 func (b *Builder) WithMemoryStorage() *Builder {
 	fileLogger := b.getOrCreateFileLogger()
@@ -96,6 +134,28 @@ func (b *Builder) WithMemoryStorage() *Builder {
 	return b
 }
 
+// WithPathMatcher sets the matcher used to match task paths against routes,
+// e.g. ginpprofhttp.NewGlobPathMatcher() for "**" globs. Defaults to the gin
+// route template matcher.
+func (b *Builder) WithPathMatcher(matcher core.PathMatcher) *Builder {
+	b.pathMatcher = matcher
+	return b
+}
+
+// WithRuntimeTaskFile persists tasks created through AdminRoutes to filePath,
+// so they survive restarts. Without it runtime tasks are kept in memory only.
+func (b *Builder) WithRuntimeTaskFile(filePath string) *Builder {
+	b.runtimeFile = filePath
+	return b
+}
+
+// WithProfiler registers a profiler that replaces the built-in profiler of
+// the same type, e.g. core.NewBlockProfilerWithOptions with a RestoreRate
+func (b *Builder) WithProfiler(profiler core.Profiler) *Builder {
+	b.profilers = append(b.profilers, profiler)
+	return b
+}
+
 // WithLogger sets a custom logger
 func (b *Builder) WithLogger(logger core.Logger) *Builder {
 	b.logger = logger
@@ -137,19 +197,29 @@ func (b *Builder) Build() *Profiler {
 		b.pathMatcher = ginpprofhttp.NewGinPathMatcher()
 	}
 
+	// Profilers are registered by the manager before its first config sync
+	options := b.options
+	options.Profilers = append(append([]core.Profiler(nil), b.options.Profilers...), b.profilers...)
+
 	// Create manager
 	manager := core.NewManager(
-		b.options,
+		options,
 		b.configProvider,
 		b.storage,
 		b.logger,
 		b.pathMatcher,
 	)
 
+	// Runtime tasks from the admin API override configured tasks with the same ID
+	runtime := config.NewRuntimeConfig(b.runtimeFile, b.logger)
+	manager.AddConfigProvider(runtime, core.SourceRuntime)
+
 	return &Profiler{
 		manager: manager,
+		runtime: runtime,
+		storage: b.storage,
 		logger:  b.logger,
-		options: b.options,
+		options: options,
 	}
 }
 
@@ -182,7 +252,15 @@ func (p *Profiler) GetStats() core.ProfilingStats {
 	return p.manager.GetStats()
 }
 
-// GetTasks returns current profiling tasks
+// GetTaskStats returns per-task profiling statistics keyed by task ID
+func (p *Profiler) GetTaskStats() map[string]core.TaskStats {
+	if p.manager == nil {
+		return make(map[string]core.TaskStats)
+	}
+	return p.manager.GetTaskStats()
+}
+
+// GetTasks returns current profiling tasks keyed by task ID
 func (p *Profiler) GetTasks() map[string]core.ProfilingTask {
 	if p.manager == nil {
 		return make(map[string]core.ProfilingTask)
@@ -190,6 +268,28 @@ func (p *Profiler) GetTasks() map[string]core.ProfilingTask {
 	return p.manager.GetTasks()
 }
 
+// GetTaskStatuses returns current profiling tasks with their quota usage keyed by task ID
+func (p *Profiler) GetTaskStatuses() map[string]core.TaskStatus {
+	if p.manager == nil {
+		return make(map[string]core.TaskStatus)
+	}
+	return p.manager.GetTaskStatuses()
+}
+
+// Storage returns the storage profiles are saved to. Storages that can read
+// profiles back also implement core.StorageReader.
+func (p *Profiler) Storage() core.Storage {
+	return p.storage
+}
+
+// QueryProfiles returns the metadata of stored profiles matching query, newest first
+func (p *Profiler) QueryProfiles(ctx context.Context, query core.ProfileQuery) ([]core.ProfilingResult, error) {
+	if p.manager == nil {
+		return nil, nil
+	}
+	return p.manager.QueryProfiles(ctx, query)
+}
+
 // IsEnabled returns whether profiling is enabled
 func (p *Profiler) IsEnabled() bool {
 	if p.manager == nil {
@@ -226,23 +326,33 @@ func (p *Profiler) StatusHandler() gin.HandlerFunc {
 		}
 
 		stats := p.manager.GetStats()
-		tasks := p.manager.GetTasks()
+		tasks := p.manager.GetTaskStatuses()
 
-		// Calculate active tasks count
+		// Calculate active and exhausted tasks count, and tasks per source
 		now := time.Now()
 		activeTasks := 0
+		exhaustedTasks := 0
+		sources := make(map[string]int)
 		for _, task := range tasks {
-			if now.Before(task.ExpiresAt) {
+			sources[task.Source]++
+			if !now.Before(task.ExpiresAt) || now.Before(task.StartsAt) {
+				continue
+			}
+			if task.Exhausted {
+				exhaustedTasks++
+			} else {
 				activeTasks++
 			}
 		}
 
 		response := gin.H{
-			"enabled":      true,
-			"stats":        stats,
-			"active_tasks": activeTasks,
-			"total_tasks":  len(tasks),
-			"profile_dir":  p.options.ProfileDir,
+			"enabled":         true,
+			"stats":           stats,
+			"active_tasks":    activeTasks,
+			"exhausted_tasks": exhaustedTasks,
+			"total_tasks":     len(tasks),
+			"sources":         sources,
+			"profile_dir":     p.options.ProfileDir,
 		}
 
 		// Include task details if requested
@@ -264,25 +374,41 @@ func (p *Profiler) TasksHandler() gin.HandlerFunc {
 			return
 		}
 
-		tasks := p.manager.GetTasks()
+		tasks := p.manager.GetTaskStatuses()
 		now := time.Now()
 
 		// Categorize tasks
-		activeTasks := make([]core.ProfilingTask, 0)
-		expiredTasks := make([]core.ProfilingTask, 0)
+		activeTasks := make([]core.TaskStatus, 0)
+		pendingTasks := make([]core.TaskStatus, 0)
+		expiredTasks := make([]core.TaskStatus, 0)
+		exhaustedTasks := make([]core.TaskStatus, 0)
 
 		for _, task := range tasks {
-			if now.Before(task.ExpiresAt) {
-				activeTasks = append(activeTasks, task)
-			} else {
+			switch {
+			case !now.Before(task.ExpiresAt):
 				expiredTasks = append(expiredTasks, task)
+			case now.Before(task.StartsAt):
+				pendingTasks = append(pendingTasks, task)
+			case task.Exhausted:
+				exhaustedTasks = append(exhaustedTasks, task)
+			default:
+				activeTasks = append(activeTasks, task)
 			}
 		}
 
+		// Tasks rejected by config validation, with the reasons
+		rejectedTasks := p.manager.GetQuarantinedTasks()
+		if rejectedTasks == nil {
+			rejectedTasks = make([]core.QuarantinedTask, 0)
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"active_tasks":  activeTasks,
-			"expired_tasks": expiredTasks,
-			"total":         len(tasks),
+			"active_tasks":    activeTasks,
+			"pending_tasks":   pendingTasks,
+			"expired_tasks":   expiredTasks,
+			"exhausted_tasks": exhaustedTasks,
+			"rejected_tasks":  rejectedTasks,
+			"total":           len(tasks),
 		})
 	}
 }
@@ -309,11 +435,99 @@ func (p *Profiler) StatsHandler() gin.HandlerFunc {
 			"total_requests":  stats.TotalRequests,
 			"profiled_count":  stats.ProfiledCount,
 			"failed_count":    stats.FailedCount,
+			"kept_count":      stats.KeptCount,
+			"discarded_count": stats.DiscardedCount,
 			"active_profiles": stats.ActiveProfiles,
 			"success_rate":    successRate,
 			"last_update":     stats.LastUpdate.Format(time.RFC3339),
+			"tasks":           p.manager.GetTaskStats(),
 		}
 
 		c.JSON(http.StatusOK, response)
 	}
 }
+
+// ProfilesHandler returns a Gin handler that queries stored profiles by their
+// metadata. Supported query parameters: profile_id (the X-Gin-Pprof-Id of a
+// triggered request), route, type, task_id, since and until
+// (RFC3339), min_latency and max_latency (e.g. "500ms"), status (e.g. "500",
+// "5xx" or "500-599") and limit.
+func (p *Profiler) ProfilesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p.manager == nil || !p.manager.IsEnabled() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Profiling not enabled",
+			})
+			return
+		}
+
+		query, err := parseProfileQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		profiles, err := p.manager.QueryProfiles(c.Request.Context(), query)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, core.ErrStorageNotReadable) {
+				status = http.StatusNotImplemented
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if profiles == nil {
+			profiles = make([]core.ProfilingResult, 0)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"profiles": profiles,
+			"total":    len(profiles),
+		})
+	}
+}
+
+// parseProfileQuery reads a profile query from the request query parameters
+func parseProfileQuery(c *gin.Context) (core.ProfileQuery, error) {
+	query := core.ProfileQuery{
+		ProfileID:   c.Query("profile_id"),
+		Route:       c.Query("route"),
+		ProfileType: c.Query("type"),
+		TaskID:      c.Query("task_id"),
+		Status:      c.Query("status"),
+	}
+
+	var err error
+	if value := c.Query("since"); value != "" {
+		if query.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			return query, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if value := c.Query("until"); value != "" {
+		if query.Until, err = time.Parse(time.RFC3339, value); err != nil {
+			return query, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if value := c.Query("min_latency"); value != "" {
+		if query.MinLatency, err = time.ParseDuration(value); err != nil {
+			return query, fmt.Errorf("invalid min_latency: %w", err)
+		}
+	}
+	if value := c.Query("max_latency"); value != "" {
+		if query.MaxLatency, err = time.ParseDuration(value); err != nil {
+			return query, fmt.Errorf("invalid max_latency: %w", err)
+		}
+	}
+	if query.Status != "" {
+		if _, _, ok := core.ParseStatus(query.Status); !ok {
+			return query, fmt.Errorf("invalid status %q", query.Status)
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 0 {
+			return query, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+
+	return query, nil
+}